@@ -0,0 +1,202 @@
+// Package anchor keeps notes attached to the code they were written about.
+//
+// When a note is created for a line, an Anchor records the line's content,
+// a few lines of surrounding context and a hash. Relocate later fuzzy-matches
+// the anchor against the current file to find where that code moved to.
+package anchor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// ContextLines is the number of lines captured above and below the anchored
+// line.
+const ContextLines = 3
+
+// minScore is the lowest match score accepted when relocating an anchor.
+const minScore = 0.6
+
+// Anchor identifies a line by its content rather than its number.
+type Anchor struct {
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+	Hash   string   `json:"hash"`
+}
+
+// Status describes where a note's anchor was found.
+type Status string
+
+const (
+	// StatusUnchanged means the anchored line is still at the recorded line.
+	StatusUnchanged Status = ""
+	// StatusMoved means the anchored line was found at a different line.
+	StatusMoved Status = "moved"
+	// StatusOrphaned means the anchored line could not be found.
+	StatusOrphaned Status = "orphaned"
+)
+
+// Result is the outcome of relocating an anchor.
+type Result struct {
+	Line   int
+	Status Status
+}
+
+// Capture returns the anchor for the 1-based line of lines, or nil when the
+// line is out of range.
+func Capture(lines []string, line int) *Anchor {
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	i := line - 1
+	return &Anchor{
+		Text:   lines[i],
+		Before: append([]string(nil), lines[max(0, i-ContextLines):i]...),
+		After:  append([]string(nil), lines[i+1:min(len(lines), i+1+ContextLines)]...),
+		Hash:   Hash(lines[i]),
+	}
+}
+
+// CaptureFile reads path and returns the anchor for its 1-based line.
+func CaptureFile(path string, line int) (*Anchor, error) {
+	lines, err := ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return Capture(lines, line), nil
+}
+
+// Hash returns the hash of a line, ignoring surrounding and repeated
+// whitespace so reindented code still matches.
+func Hash(line string) string {
+	sum := sha256.Sum256([]byte(normalize(line)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ReadLines returns the lines of the file at path.
+func ReadLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// Relocate finds the anchored line in lines, starting from the line it was
+// last known at. Exact matches of the line and its context win; otherwise
+// the best fuzzy match above a threshold is used, preferring lines close to
+// the old position. When nothing matches well enough the note is orphaned
+// and keeps its old line.
+func Relocate(lines []string, a *Anchor, line int) Result {
+	if a == nil {
+		return Result{Line: line}
+	}
+	// the line alone may repeat, e.g. a closing brace, so its context must
+	// be unchanged too
+	if line >= 1 && line <= len(lines) && Hash(lines[line-1]) == a.Hash && sameContext(lines, line-1, a) {
+		return Result{Line: line}
+	}
+
+	want := normalize(a.Text)
+	bestLine, bestScore := 0, 0.0
+	for i := range lines {
+		score := 0.7*similarity(want, normalize(lines[i])) + 0.3*contextScore(lines, i, a)
+		// prefer candidates near the old position when scores tie
+		score -= 0.0001 * float64(abs(i+1-line))
+		if score > bestScore {
+			bestLine, bestScore = i+1, score
+		}
+	}
+
+	if bestScore < minScore {
+		return Result{Line: line, Status: StatusOrphaned}
+	}
+	if bestLine == line {
+		return Result{Line: line}
+	}
+	return Result{Line: bestLine, Status: StatusMoved}
+}
+
+// sameContext reports whether the lines around index i hash the same as the
+// anchor's context.
+func sameContext(lines []string, i int, a *Anchor) bool {
+	for k, want := range a.Before {
+		j := i - len(a.Before) + k
+		if j < 0 || Hash(lines[j]) != Hash(want) {
+			return false
+		}
+	}
+	for k, want := range a.After {
+		j := i + 1 + k
+		if j >= len(lines) || Hash(lines[j]) != Hash(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// contextScore compares the lines around index i with the anchor's context.
+func contextScore(lines []string, i int, a *Anchor) float64 {
+	total, matched := 0, 0.0
+	for k, want := range a.Before {
+		j := i - len(a.Before) + k
+		total++
+		if j >= 0 {
+			matched += similarity(normalize(want), normalize(lines[j]))
+		}
+	}
+	for k, want := range a.After {
+		j := i + 1 + k
+		total++
+		if j < len(lines) {
+			matched += similarity(normalize(want), normalize(lines[j]))
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return matched / float64(total)
+}
+
+// similarity returns the Dice coefficient of the character bigrams of a and
+// b, between 0 and 1.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < len(a)-1; i++ {
+		counts[a[i:i+2]]++
+	}
+	common := 0
+	for i := 0; i < len(b)-1; i++ {
+		if counts[b[i:i+2]] > 0 {
+			counts[b[i:i+2]]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)-1+len(b)-1)
+}
+
+func normalize(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package anchor
+
+import (
+	"slices"
+	"testing"
+)
+
+var original = []string{
+	"package main",
+	"",
+	"func open() {",
+	"	f := load()",
+	"	defer f.Close()",
+	"}",
+	"",
+	"func save() {",
+	"	f := create()",
+	"	defer f.Close()",
+	"}",
+}
+
+// edit returns original with lines replaced, inserted or removed at index i.
+func edit(i, remove int, insert ...string) []string {
+	return slices.Concat(original[:i], insert, original[i+remove:])
+}
+
+func TestRelocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		anchor *Anchor
+		line   int
+		want   Result
+	}{
+		{"no anchor", original, nil, 4, Result{Line: 4}},
+		{"unchanged", original, Capture(original, 4), 4, Result{Line: 4}},
+		{"reindented", edit(3, 1, "		f := load()"), Capture(original, 4), 4, Result{Line: 4}},
+		{"lines inserted above", edit(0, 0, "// Package main", "// opens files"), Capture(original, 4), 4, Result{Line: 6, Status: StatusMoved}},
+		{"lines removed above", edit(0, 2), Capture(original, 9), 9, Result{Line: 7, Status: StatusMoved}},
+		{"line edited", edit(3, 1, "	f := loadFile()"), Capture(original, 4), 4, Result{Line: 4}},
+		{"line deleted", edit(2, 4), Capture(original, 4), 4, Result{Line: 4, Status: StatusOrphaned}},
+		{"file shrank", original[5:], Capture(original, 9), 9, Result{Line: 4, Status: StatusMoved}},
+		{"empty file", []string{}, Capture(original, 4), 4, Result{Line: 4, Status: StatusOrphaned}},
+
+		// the same text at the old line, but not the same code: the context
+		// tells the two apart
+		{"repeated line", edit(0, 5), Capture(original, 6), 6, Result{Line: 1, Status: StatusMoved}},
+		{"repeated line with context", edit(2, 0, "func close() {", "	f := load()", "	defer f.Close()", "}", ""), Capture(original, 10), 10, Result{Line: 15, Status: StatusMoved}},
+	}
+	for _, tt := range tests {
+		if got := Relocate(tt.lines, tt.anchor, tt.line); got != tt.want {
+			t.Errorf("%s: Relocate(line %d) = %+v, want %+v", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	a := Capture(original, 2)
+	if a.Text != "" || !slices.Equal(a.Before, original[:1]) || !slices.Equal(a.After, original[2:5]) {
+		t.Errorf("Capture(2) = %+v", a)
+	}
+	for _, line := range []int{0, -1, len(original) + 1} {
+		if a := Capture(original, line); a != nil {
+			t.Errorf("Capture(%d) = %+v, want nil", line, a)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package anchor
+
+import "path/filepath"
+
+// Locator relocates anchors against the files of a project, reading each
+// file at most once.
+type Locator struct {
+	root  string
+	files map[string][]string
+}
+
+// NewLocator returns a Locator for files relative to root.
+func NewLocator(root string) *Locator {
+	return &Locator{root: root, files: map[string][]string{}}
+}
+
+// SetLines makes the Locator use lines as the contents of file, such as
+// the unsaved text of a file open in an editor.
+func (l *Locator) SetLines(file string, lines []string) {
+	l.files[file] = lines
+}
+
+// Locate relocates a in file, which is relative to the project root. Notes
+// without an anchor keep their line; anchors into files that can no longer
+// be read are orphaned.
+func (l *Locator) Locate(file string, a *Anchor, line int) Result {
+	if a == nil || file == "" {
+		return Result{Line: line}
+	}
+
+	lines, ok := l.files[file]
+	if !ok {
+		var err error
+		lines, err = ReadLines(filepath.Join(l.root, filepath.FromSlash(file)))
+		if err != nil {
+			lines = nil
+		}
+		l.files[file] = lines
+	}
+	if lines == nil {
+		return Result{Line: line, Status: StatusOrphaned}
+	}
+	return Relocate(lines, a, line)
+}
@@ -4,37 +4,68 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"spjoes/notes/store"
 )
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
-	Use:   "add",
+	Use:   "add [message]",
 	Short: "Add a new note",
-	Long:  `Adds a new note to your project. Save a new note by providing a message after the add command surrounded by quotes.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Adds a new note to your project. Save a new note by providing a message after the add command surrounded by quotes.
+
+Without a message, or with --editor, the note is written in your editor
+($VISUAL or $EDITOR), where it can span several lines of markdown. The file,
+line and tags can be changed in the front matter at the top.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			fmt.Println("Please provide a note message")
-			return
+		file, span := store.SplitFileSpan(noteFile)
+		if noteLine != "" {
+			if span.Line > 0 {
+				fmt.Println("Please give the line either with --line or as part of --file, not both")
+				return
+			}
+			var err error
+			if span, err = store.ParseSpan(noteLine); err != nil {
+				fmt.Println("Error: ", err)
+				return
+			}
 		}
 
-		note := args[0]
-		err := SaveNote(note, noteFile, noteLine, noteTags)
-		fmt.Println("Note Saved Successfully")
-		if err != nil {
+		note := ""
+		if len(args) > 0 {
+			note = args[0]
+		}
+		tags := noteTags
+		if note == "" || noteEditor {
+			c, err := composeInEditor(composed{Message: note, File: file, Span: span, Tags: tags})
+			if err != nil {
+				fmt.Println("Error: ", err)
+				return
+			}
+			note, file, span, tags = c.Message, c.File, c.Span, c.Tags
+		}
+
+		if err := SaveNote(note, file, span, tags, noteNoGit); err != nil {
 			fmt.Println("Error saving note: ", err)
+			return
 		}
+		fmt.Println("Note Saved Successfully")
 	},
 }
 
 var noteFile string
-var noteLine int
+var noteLine string
 var noteTags []string
+var noteNoGit bool
+var noteEditor bool
 
 func init() {
 	rootCmd.AddCommand(addCmd)
 
-	addCmd.Flags().StringVarP(&noteFile, "file", "f", "", "Optional file to associate with the note (e.g. --file cmd/root.go)")
-	addCmd.Flags().IntVarP(&noteLine, "line", "l", 0, "Optional line number in the file to associate with the note (e.g. --line 10)")
+	addCmd.Flags().StringVarP(&noteFile, "file", "f", "", "Optional file to associate with the note, optionally with lines (e.g. --file cmd/root.go or --file cmd/root.go:10-24)")
+	addCmd.Flags().StringVarP(&noteLine, "line", "l", "", "Optional line or line range in the file to associate with the note (e.g. --line 10, --line 10-24 or --line 10:5-24:12)")
 	addCmd.Flags().StringSliceVarP(&noteTags, "tags", "t", []string{}, "Optional comma-separated tags for the note (e.g. --tags bug,urgent)")
+	addCmd.Flags().BoolVarP(&noteEditor, "editor", "e", false, "Write the note in your editor")
+	addCmd.Flags().BoolVar(&noteNoGit, "no-git", false, "Don't record the current git commit, branch and author")
 }
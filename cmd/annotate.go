@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/highlight"
+)
+
+var annotateOnlyNoted bool
+var annotateContext int
+var annotateRaw bool
+
+// annotateCmd represents the annotate command
+var annotateCmd = &cobra.Command{
+	Use:   "annotate <file>",
+	Short: "Print a file with its notes interleaved",
+	Long: `Prints a file with line numbers and syntax highlighting, and every note
+linked to it in a box above the line it refers to. Noted lines are marked
+with ▶. Notes follow their code when it has moved, like in notes list.
+
+With --only-noted, only the noted lines and --context lines around them are
+printed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if annotateContext < 0 {
+			fmt.Println("Error: --context must not be negative")
+			return
+		}
+
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		root := s.Root()
+		file := resolveFile(root, args[0])
+
+		lines, err := anchor.ReadLines(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			fmt.Println("Error reading file:", err)
+			return
+		}
+		notes, err := s.Query(func(n Note) bool { return n.File == file })
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		// callouts go above the first line of their note; notes without a
+		// line go at the top and notes past the end at the bottom
+		callouts := map[int][]locatedNote{}
+		covered := make([]bool, len(lines)+2)
+		locator := anchor.NewLocator(root)
+		for _, stored := range notes {
+			n, status := locate(locator, stored)
+			at := min(max(n.Line, 0), len(lines)+1)
+			callouts[at] = append(callouts[at], locatedNote{Note: n, Status: status, StoredLine: stored.Line})
+			if status != anchor.StatusOrphaned {
+				for i := max(n.Line, 1); i <= min(n.LastLine(), len(lines)); i++ {
+					covered[i] = true
+				}
+			}
+		}
+		for _, c := range callouts {
+			slices.SortStableFunc(c, func(a, b locatedNote) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		}
+
+		// with --only-noted, show the lines near a note or its callout
+		shown := make([]bool, len(lines)+2)
+		for i := range shown {
+			shown[i] = !annotateOnlyNoted
+		}
+		if annotateOnlyNoted {
+			for i := 1; i < len(covered); i++ {
+				if covered[i] || len(callouts[i]) > 0 {
+					for j := max(0, i-annotateContext); j <= min(len(lines)+1, i+annotateContext); j++ {
+						shown[j] = true
+					}
+				}
+			}
+		}
+
+		width := len(fmt.Sprint(len(lines)))
+		gutter := color.New(color.FgHiBlack).SprintFunc()
+		marker := color.New(color.FgHiYellow, color.Bold).SprintFunc()
+		tokens := highlight.Lines(highlight.ForFile(file), lines)
+
+		fmt.Printf("%s %s\n\n", color.New(color.Bold).Sprint(file), gutter(fmt.Sprintf("(%d note(s))", len(notes))))
+		printCallouts(callouts[0], width)
+		skipped := false
+		for i := 1; i <= len(lines); i++ {
+			if !shown[i] {
+				skipped = true
+				continue
+			}
+			if skipped && i > 1 {
+				fmt.Printf("  %*s %s\n", width, "", gutter("┆"))
+			}
+			skipped = false
+
+			printCallouts(callouts[i], width)
+			num := fmt.Sprintf("%*d", width, i)
+			if covered[i] {
+				fmt.Printf("%s %s %s %s\n", marker("▶"), marker(num), gutter("│"), colorTokens(tokens[i-1]))
+			} else {
+				fmt.Printf("  %s %s %s\n", gutter(num), gutter("│"), colorTokens(tokens[i-1]))
+			}
+		}
+		if skipped && len(callouts[len(lines)+1]) > 0 {
+			fmt.Printf("  %*s %s\n", width, "", gutter("┆"))
+		}
+		printCallouts(callouts[len(lines)+1], width)
+	},
+}
+
+// printCallouts prints notes as boxes lined up with the gutter of a file
+// whose line numbers are width digits wide.
+func printCallouts(notes []locatedNote, width int) {
+	border := color.New(color.FgYellow).SprintFunc()
+	meta := color.New(color.FgHiBlack).SprintFunc()
+	indent := strings.Repeat(" ", width+3)
+
+	for _, n := range notes {
+		header := []string{color.HiCyanString(n.ShortID())}
+		if n.Line > 0 && n.LastLine() > n.Line {
+			header = append(header, meta(fmt.Sprintf("lines %d-%d", n.Line, n.LastLine())))
+		}
+		switch n.Status {
+		case anchor.StatusMoved:
+			header = append(header, color.YellowString("moved from line %d", n.StoredLine))
+		case anchor.StatusOrphaned:
+			header = append(header, color.RedString("orphaned"))
+		}
+		if len(n.Tags) > 0 {
+			header = append(header, color.GreenString(strings.Join(n.Tags, ", ")))
+		}
+		if n.Author != "" {
+			header = append(header, meta(gitinfo.AuthorName(n.Author)))
+		}
+		if !n.CreatedAt.IsZero() {
+			header = append(header, meta(n.CreatedAt.Local().Format("2006-01-02")))
+		}
+
+		fmt.Printf("%s%s %s\n", indent, border("╭─"), strings.Join(header, meta(" · ")))
+		message := renderMessage(n.Message, annotateRaw, len(indent)+2)
+		for _, line := range strings.Split(message, "\n") {
+			fmt.Printf("%s%s %s\n", indent, border("│"), line)
+		}
+		fmt.Printf("%s%s\n", indent, border("╰─"))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+
+	annotateCmd.Flags().BoolVar(&annotateOnlyNoted, "only-noted", false, "Only print the lines around notes")
+	annotateCmd.Flags().IntVarP(&annotateContext, "context", "C", 3, "Number of lines to print around noted lines with --only-noted")
+	annotateCmd.Flags().BoolVar(&annotateRaw, "raw", false, "Print messages as written instead of rendering their markdown")
+}
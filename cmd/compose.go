@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"spjoes/notes/config"
+	"spjoes/notes/editor"
+	"spjoes/notes/store"
+)
+
+// errEmptyNote is returned when a note composed in the editor has no text.
+var errEmptyNote = errors.New("empty note, nothing saved")
+
+// composed is a note as written in the editor.
+type composed struct {
+	Message string
+	File    string
+	Span    store.Span
+	Tags    []string
+}
+
+const composeHelp = `# The lines between the dashes set the file, line and tags of the note;
+# leave a value empty for none. Write the note below the closing dashes;
+# markdown is welcome. Save an empty note to cancel.
+`
+
+// composeTemplate formats a note for editing: front matter with its file,
+// line and tags, followed by the message.
+func composeTemplate(c composed) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString(composeHelp)
+	fmt.Fprintf(&b, "file: %s\n", c.File)
+	fmt.Fprintf(&b, "line: %s\n", c.Span)
+	fmt.Fprintf(&b, "tags: %s\n", strings.Join(c.Tags, ", "))
+	b.WriteString("---\n\n")
+	b.WriteString(c.Message)
+	if c.Message != "" && !strings.HasSuffix(c.Message, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// messageLine returns the line of a template the message starts on.
+func messageLine(tmpl string) int {
+	header := tmpl[:strings.Index(tmpl, "\n---\n")+len("\n---\n\n")]
+	return strings.Count(header, "\n") + 1
+}
+
+// parseComposed reads a note back from the editor. Text without front
+// matter is taken as the message alone.
+func parseComposed(text string, c composed) (composed, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		front, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			if front, found = strings.CutSuffix(rest, "\n---"); !found {
+				return c, errors.New(`front matter is not closed with "---"`)
+			}
+		}
+		text = body
+
+		for _, line := range strings.Split(front, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return c, fmt.Errorf("front matter line %q is not \"key: value\"", line)
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "file":
+				c.File = value
+			case "line", "lines":
+				c.Span = store.Span{}
+				if value != "" {
+					sp, err := store.ParseSpan(value)
+					if err != nil {
+						return c, err
+					}
+					c.Span = sp
+				}
+			case "tags":
+				c.Tags = nil
+				for _, tag := range strings.Split(value, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						c.Tags = append(c.Tags, tag)
+					}
+				}
+			default:
+				return c, fmt.Errorf("unknown front matter key %q (expected file, line or tags)", key)
+			}
+		}
+	}
+
+	// drop blank lines around the message, keeping the indentation of its
+	// first line, e.g. of an indented code block
+	msg := strings.TrimRight(text, " \t\n")
+	for {
+		line, rest, ok := strings.Cut(msg, "\n")
+		if !ok || strings.TrimSpace(line) != "" {
+			break
+		}
+		msg = rest
+	}
+	c.Message = msg
+	if c.Message == "" {
+		return c, errEmptyNote
+	}
+	return c, nil
+}
+
+// composeInEditor opens the user's editor on a note and returns it as
+// saved. If what was written cannot be read back it is kept in a file named
+// in the error.
+func composeInEditor(c composed) (composed, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return c, err
+	}
+	tmpl := composeTemplate(c)
+	text, err := editor.EditText(editor.Find(cfg.Editor), cfg.Editors, "NOTE_EDITMSG-*.md", tmpl, messageLine(tmpl))
+	if err != nil {
+		return c, err
+	}
+
+	parsed, err := parseComposed(text, c)
+	if err != nil && !errors.Is(err, errEmptyNote) {
+		if f, ferr := os.CreateTemp("", "NOTE_EDITMSG-*.md"); ferr == nil {
+			f.WriteString(text)
+			f.Close()
+			err = fmt.Errorf("%w (your text is in %s)", err, f.Name())
+		}
+	}
+	return parsed, err
+}
@@ -1,16 +1,19 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"spjoes/notes/query"
+	"spjoes/notes/store"
 )
 
 var forceDelete bool
 var deleteTag string
+var deleteQuery string
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -18,122 +21,95 @@ var deleteCmd = &cobra.Command{
 	Short: "Delete a note by ID",
 	Long:  `Deletes a note from your project.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		root, err := os.Getwd()
+		s, err := openStore()
 		if err != nil {
-			fmt.Println("Error getting working directory:", err)
-			return
-		}
-
-		notesPath := filepath.Join(root, ".notes", "notes.json")
-		if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-			fmt.Println("No notes file found.")
+			fmt.Println("Error opening notes:", err)
 			return
 		}
 
-		data, err := os.ReadFile(notesPath)
-		if err != nil {
-			fmt.Println("Error reading notes file:", err)
+		if cmd.Flags().Changed("query") && strings.TrimSpace(deleteQuery) == "" {
+			// a blank query matches every note
+			fmt.Println("Error: --query must not be empty")
 			return
 		}
 
-		var notes []Note
-		if err := json.Unmarshal(data, &notes); err != nil {
-			fmt.Println("Error parsing notes:", err)
-			return
-		}
-
-		if deleteTag != "" {
-			// Delete by tag
-			var updatedNotes []Note
-			deletedCount := 0
-
-			for _, note := range notes {
-				hasTag := false
-				for _, tag := range note.Tags {
-					if tag == deleteTag {
-						hasTag = true
-						break
-					}
-				}
-
-				if hasTag {
-					if !forceDelete {
-						fmt.Printf("Delete note \"%s\" (file: %s)? (y/N): ", note.Message, note.File)
-						var input string
-						fmt.Scanln(&input)
-						if input != "y" && input != "Y" {
-							updatedNotes = append(updatedNotes, note)
-							continue
-						}
-					}
-					deletedCount++
-					continue
+		if deleteTag != "" || deleteQuery != "" {
+			// Delete by tag or query
+			q, err := query.Parse(deleteQuery)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			what := fmt.Sprintf("matching \"%s\"", deleteQuery)
+			if deleteTag != "" {
+				what = fmt.Sprintf("with tag \"%s\"", deleteTag)
+				if deleteQuery != "" {
+					what += fmt.Sprintf(" and matching \"%s\"", deleteQuery)
 				}
-
-				updatedNotes = append(updatedNotes, note)
 			}
 
-			if deletedCount == 0 {
-				fmt.Printf("No notes found with tag \"%s\"\n", deleteTag)
+			tagged, err := s.Query(func(n Note) bool {
+				return (deleteTag == "" || n.HasTag(deleteTag)) && q.Match(n)
+			})
+			if err != nil {
+				fmt.Println("Error reading notes:", err)
 				return
 			}
 
-			updatedData, err := json.MarshalIndent(updatedNotes, "", "  ")
-			if err != nil {
-				fmt.Println("Error saving updated notes:", err)
-				return
+			var ids []string
+			for _, note := range tagged {
+				if !forceDelete {
+					fmt.Printf("Delete note \"%s\" (file: %s)? (y/N): ", firstLine(note.Message), note.File)
+					var input string
+					fmt.Scanln(&input)
+					if input != "y" && input != "Y" {
+						continue
+					}
+				}
+				ids = append(ids, note.ID)
 			}
 
-			err = os.WriteFile(notesPath, updatedData, 0644)
-			if err != nil {
+			if len(ids) == 0 {
+				fmt.Printf("No notes found %s\n", what)
+				return
+			}
+			if _, err := s.Apply(store.Batch{Delete: ids}); err != nil {
 				fmt.Println("Error writing updated notes:", err)
 				return
 			}
 
-			fmt.Printf("Deleted %d note(s) with tag \"%s\".\n", deletedCount, deleteTag)
+			fmt.Printf("Deleted %d note(s) %s.\n", len(ids), what)
 			return
 		}
 
 		// Default: Delete by ID
 		if len(args) == 0 {
-			fmt.Println("Please provide a note ID or use --tag to delete all notes with a given tag")
+			fmt.Println("Please provide a note ID or use --tag or --query to delete several notes")
 			return
 		}
 
 		idToDelete := args[0]
-		var updatedNotes []Note
-		deleted := false
-
-		for _, note := range notes {
-			if note.ID[:8] == idToDelete || note.ID == idToDelete {
-				if !forceDelete {
-					fmt.Printf("Are you sure you want to delete note \"%s\"? (y/N): ", note.Message)
-					var input string
-					fmt.Scanln(&input)
-					if input != "y" && input != "Y" {
-						fmt.Println("Aborted.")
-						return
-					}
-				}
-				deleted = true
-				continue
-			}
-			updatedNotes = append(updatedNotes, note)
-		}
-
-		if !deleted {
+		note, err := s.Get(idToDelete)
+		if errors.Is(err, store.ErrNotFound) {
 			fmt.Printf("No note found with ID %s\n", idToDelete)
 			return
 		}
-
-		updatedData, err := json.MarshalIndent(updatedNotes, "", "  ")
 		if err != nil {
-			fmt.Println("Error saving updated notes:", err)
+			fmt.Println("Error reading notes:", err)
 			return
 		}
 
-		err = os.WriteFile(notesPath, updatedData, 0644)
-		if err != nil {
+		if !forceDelete {
+			fmt.Printf("Are you sure you want to delete note \"%s\"? (y/N): ", firstLine(note.Message))
+			var input string
+			fmt.Scanln(&input)
+			if input != "y" && input != "Y" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+
+		if err := s.Delete(note.ID); err != nil {
 			fmt.Println("Error writing updated notes:", err)
 			return
 		}
@@ -146,5 +122,6 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 
 	deleteCmd.Flags().StringVarP(&deleteTag, "tag", "t", "", "Delete all notes with a given tag")
+	deleteCmd.Flags().StringVarP(&deleteQuery, "query", "q", "", "Delete all notes matching a query (see notes list --help)")
 	deleteCmd.Flags().BoolVarP(&forceDelete, "yes", "y", false, "Delete without confirmation")
 }
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/store"
+)
+
+var doctorFix bool
+var doctorClearMissing bool
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the notes store for problems and repair them",
+	Long: `Checks the notes store for invalid JSON, unresolved merge conflicts,
+duplicate or missing IDs, missing creation times and links to files that no
+longer exist.
+
+Run with --fix to repair what can be repaired. The previous contents are
+kept in .notes/backups.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		root := s.Root()
+		fmt.Printf("Checking %s\n\n", filepath.Join(root, ".notes"))
+
+		d, err := store.Diagnose(s, store.DoctorOptions{ClearMissingFiles: doctorClearMissing, Fix: doctorFix})
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		if len(d.Problems) == 0 {
+			fmt.Println(color.GreenString("No problems found"))
+			return
+		}
+
+		fixable := 0
+		for _, p := range d.Problems {
+			mark := color.YellowString("!")
+			if p.Fixable {
+				mark = color.RedString("✗")
+				fixable++
+			}
+			subject := p.Kind
+			if p.NoteID != "" {
+				subject = fmt.Sprintf("%s %s", p.Kind, color.HiCyanString(Note{ID: p.NoteID}.ShortID()))
+			}
+			fmt.Printf("%s %s: %s\n", mark, subject, p.Message)
+		}
+		fmt.Printf("\n%d problem(s) found, %d fixable.\n", len(d.Problems), fixable)
+
+		if !doctorFix {
+			if fixable > 0 {
+				command := "notes doctor --fix"
+				if doctorClearMissing {
+					command = "notes doctor --clear-missing --fix"
+				}
+				fmt.Printf("Run `%s` to repair them.\n", command)
+			}
+			return
+		}
+
+		if err := d.Repair(s); err != nil {
+			fmt.Println("Error repairing notes:", err)
+			return
+		}
+		if fixable > 0 {
+			fmt.Printf("Repaired. The previous version was saved in %s\n", store.BackupDir(root))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair the problems that can be fixed automatically")
+	doctorCmd.Flags().BoolVar(&doctorClearMissing, "clear-missing", false, "With --fix, unlink notes from files that no longer exist")
+}
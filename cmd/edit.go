@@ -4,18 +4,19 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"spjoes/notes/store"
 )
 
 var (
 	editMessage string
 	editFile    string
 	editTags    []string
+	editEditor  bool
 )
 
 // editCmd represents the edit command
@@ -25,79 +26,58 @@ var editCmd = &cobra.Command{
 	Long: `Edit an existing note in the current project.
 	
 You must supply the note ID (first 8 chars or full). 
-Provide any of --message, --file, or --tags to update just those fields.`,
+Provide any of --message, --file, or --tags to update just those fields.
+With --editor, the note opens in your editor to change its message, file,
+line and tags together.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		idToEdit := args[0]
 
-		root, err := os.Getwd()
+		s, err := openStore()
 		if err != nil {
-			fmt.Println("Error getting current directory:", err)
+			fmt.Println("Error opening notes:", err)
 			return
 		}
 
-		notesPath := filepath.Join(root, ".notes", "notes.json")
-		if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-			fmt.Println("No notes found in current project")
+		note, err := s.Get(idToEdit)
+		if errors.Is(err, store.ErrNotFound) {
+			fmt.Printf("No note found with ID %s\n", idToEdit)
 			return
 		}
-
-		data, err := os.ReadFile(notesPath)
 		if err != nil {
-			fmt.Println("Error reading notes file:", err)
+			fmt.Println("Error reading notes:", err)
 			return
 		}
 
-		var notes []Note
-		if err := json.Unmarshal(data, &notes); err != nil {
-			fmt.Println("Error parsing notes:", err)
-			return
+		if editMessage != "" {
+			note.Message = editMessage
 		}
-
-		edited := false
-		for i, n := range notes {
-			short := n.ID
-			if len(short) > 8 {
-				short = short[:8]
-			}
-
-			if short == idToEdit || n.ID == idToEdit {
-				if editMessage != "" {
-					notes[i].Message = editMessage
-				}
-				if cmd.Flags().Changed("file") {
-					if editFile == "" {
-						notes[i].File = ""
-					} else {
-						if rel, err := filepath.Rel(root, editFile); err == nil {
-							notes[i].File = rel
-						} else {
-							notes[i].File = editFile
-						}
-					}
-				}
-
-				if cmd.Flags().Changed("tags") {
-					notes[i].Tags = editTags
-				}
-				notes[i].CreatedAt = n.CreatedAt // n.CreatedAt is the original createdAt. We don't want to change it.
-				edited = true
-				break
+		if cmd.Flags().Changed("file") {
+			file, span := store.SplitFileSpan(editFile)
+			if span.Line > 0 || file == "" {
+				note.SetSpan(span)
 			}
+			relink(s.Root(), &note, resolveFile(s.Root(), file))
 		}
-
-		if !edited {
-			fmt.Printf("No note found with ID %s\n", idToEdit)
-			return
+		if cmd.Flags().Changed("tags") {
+			note.Tags = editTags
 		}
 
-		out, err := json.MarshalIndent(notes, "", "  ")
-		if err != nil {
-			fmt.Println("Error marshalling notes:", err)
-			return
+		if editEditor {
+			before := composed{Message: note.Message, File: note.File, Span: note.Span(), Tags: note.Tags}
+			after, err := composeInEditor(before)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			note.Message, note.Tags = after.Message, after.Tags
+			if after.File != before.File || after.Span != before.Span {
+				note.SetSpan(after.Span)
+				relink(s.Root(), &note, resolveFile(s.Root(), after.File))
+			}
 		}
 
-		if err := os.WriteFile(notesPath, out, 0644); err != nil {
+		if err := s.Update(note); err != nil {
 			fmt.Println("Error writing notes:", err)
 			return
 		}
@@ -110,8 +90,9 @@ func init() {
 	rootCmd.AddCommand(editCmd)
 
 	editCmd.Flags().StringVarP(&editMessage, "message", "m", "", "Update note message")
-	editCmd.Flags().StringVarP(&editFile, "file", "f", "", "New file to associate (optional)")
+	editCmd.Flags().StringVarP(&editFile, "file", "f", "", "New file to associate, optionally with lines as path:42-67 (optional)")
 	editCmd.Flags().StringSliceVarP(&editTags, "tags", "t", []string{}, "New comma-separated tags (optional)")
+	editCmd.Flags().BoolVarP(&editEditor, "editor", "e", false, "Edit the note in your editor")
 
 	// Here you will define your flags and configuration settings.
 
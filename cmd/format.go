@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"spjoes/notes/anchor"
+)
+
+// locatedNote is a note whose lines were resolved against the current
+// contents of its file.
+type locatedNote struct {
+	Note
+	Status anchor.Status
+	// StoredLine is the line recorded in the store before relocation.
+	StoredLine int
+}
+
+// noteRecord is the stable schema notes are exported in by list --format.
+// Fields are only ever added to it, never renamed or removed.
+type noteRecord struct {
+	ID         string    `json:"id"`
+	ShortID    string    `json:"short_id"`
+	Message    string    `json:"message"`
+	File       string    `json:"file"`
+	Line       int       `json:"line"`
+	Column     int       `json:"column"`
+	EndLine    int       `json:"end_line"`
+	EndColumn  int       `json:"end_column"`
+	StoredLine int       `json:"stored_line"`
+	Status     string    `json:"status"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"created_at"`
+	Commit     string    `json:"commit"`
+	Branch     string    `json:"branch"`
+	Author     string    `json:"author"`
+}
+
+func newNoteRecord(n locatedNote) noteRecord {
+	status := string(n.Status)
+	if status == "" && n.Line > 0 {
+		status = "ok"
+	}
+	tags := n.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return noteRecord{
+		ID:         n.ID,
+		ShortID:    n.ShortID(),
+		Message:    n.Message,
+		File:       n.File,
+		Line:       n.Line,
+		Column:     n.Column,
+		EndLine:    n.LastLine(),
+		EndColumn:  n.EndColumn,
+		StoredLine: n.StoredLine,
+		Status:     status,
+		Tags:       tags,
+		CreatedAt:  n.CreatedAt,
+		Commit:     n.Commit,
+		Branch:     n.Branch,
+		Author:     n.Author,
+	}
+}
+
+var recordColumns = []string{
+	"id", "short_id", "message", "file", "line", "column", "end_line", "end_column",
+	"stored_line", "status", "tags", "created_at", "commit", "branch", "author",
+}
+
+func (r noteRecord) columns() []string {
+	return []string{
+		r.ID, r.ShortID, r.Message, r.File, itoa(r.Line), itoa(r.Column), itoa(r.EndLine), itoa(r.EndColumn),
+		itoa(r.StoredLine), r.Status, strings.Join(r.Tags, ","), r.CreatedAt.Format(time.RFC3339), r.Commit, r.Branch, r.Author,
+	}
+}
+
+// itoa formats n, leaving zero ("not set") empty.
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// writeNotes writes notes to w in one of the machine-readable formats, or
+// through a text/template when format contains "{{".
+func writeNotes(w io.Writer, format string, notes []locatedNote) error {
+	records := make([]noteRecord, len(notes))
+	for i, n := range notes {
+		records[i] = newNoteRecord(n)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(recordColumns); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write(r.columns()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case "tsv":
+		if _, err := fmt.Fprintln(w, strings.Join(recordColumns, "\t")); err != nil {
+			return err
+		}
+		for _, r := range records {
+			cols := r.columns()
+			for i := range cols {
+				cols[i] = tsvEscape(cols[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(cols, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tLOCATION\tTAGS\tCREATED\tMESSAGE")
+		for i, r := range records {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ShortID, notes[i].Location(), strings.Join(r.Tags, ","),
+				r.CreatedAt.Format("2006-01-02 15:04"), firstLine(r.Message))
+		}
+		return tw.Flush()
+	}
+
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format %q (expected text, json, jsonl, csv, tsv, table or a Go template)", format)
+	}
+	tmpl, err := template.New("note").Funcs(template.FuncMap{
+		"join":      strings.Join,
+		"firstLine": firstLine,
+	}).Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := tmpl.Execute(w, r); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// tsvEscape escapes the characters that would break a TSV row.
+func tsvEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
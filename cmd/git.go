@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/gitinfo"
+)
+
+const mergeDriverName = "notes"
+
+var installGlobal bool
+var installCommand string
+
+// gitCmd represents the git command
+var gitCmd = &cobra.Command{
+	Use:   "git",
+	Short: "Set up git integration for the notes store",
+}
+
+// installMergeDriverCmd represents the git install-merge-driver command
+var installMergeDriverCmd = &cobra.Command{
+	Use:   "install-merge-driver",
+	Short: "Let git merge .notes/notes.json with notes merge-driver",
+	Long: `Registers "notes merge-driver" as a git merge driver and marks
+.notes/notes.json to use it in .gitattributes, so notes added on different
+branches merge without manual conflict resolution.
+
+Every clone needs the driver registered in its git config; .gitattributes is
+shared through the repository.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := projectRoot()
+		if err != nil {
+			fmt.Println("Error finding project root:", err)
+			return
+		}
+
+		scope := "--local"
+		if installGlobal {
+			scope = "--global"
+		}
+		driver := installCommand + " merge-driver %O %A %B"
+		if _, err := gitinfo.Run(root, "config", scope, "merge."+mergeDriverName+".name", "notes three-way merge of notes.json"); err != nil {
+			fmt.Println("Error configuring git:", err)
+			return
+		}
+		if _, err := gitinfo.Run(root, "config", scope, "merge."+mergeDriverName+".driver", driver); err != nil {
+			fmt.Println("Error configuring git:", err)
+			return
+		}
+
+		added, err := addGitAttribute(root, ".notes/notes.json merge="+mergeDriverName)
+		if err != nil {
+			fmt.Println("Error updating .gitattributes:", err)
+			return
+		}
+
+		fmt.Printf("Registered merge driver %q (%s)\n", mergeDriverName, driver)
+		if added {
+			fmt.Println("Added .notes/notes.json to .gitattributes; commit it to share the setting")
+		} else {
+			fmt.Println(".gitattributes already routes .notes/notes.json to the driver")
+		}
+	},
+}
+
+// addGitAttribute appends line to the .gitattributes file in root unless it
+// is already present.
+func addGitAttribute(root, line string) (bool, error) {
+	path := filepath.Join(root, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for _, l := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(l) == line {
+			return false, nil
+		}
+	}
+
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += line + "\n"
+	return true, os.WriteFile(path, []byte(content), 0644)
+}
+
+func init() {
+	rootCmd.AddCommand(gitCmd)
+	gitCmd.AddCommand(installMergeDriverCmd)
+
+	installMergeDriverCmd.Flags().BoolVar(&installGlobal, "global", false, "Register the driver in the global git config instead of the repository's")
+	installMergeDriverCmd.Flags().StringVar(&installCommand, "command", "notes", "Command git should run for the notes binary")
+}
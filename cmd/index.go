@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/search"
+	"spjoes/notes/store"
+)
+
+var indexOff bool
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Enable and rebuild the full-text search index",
+	Long: `Enables the full-text search index of the current project and rebuilds it
+from scratch. The index is kept in .notes/index, is updated whenever notes
+are written, and makes "notes search" fast on large stores. It is local to
+each checkout and never committed.
+
+With --off, the index is disabled and removed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		root := s.Root()
+
+		if indexOff {
+			if err := search.Remove(root); err != nil {
+				fmt.Println("Error removing search index:", err)
+				return
+			}
+			fmt.Println("Search index disabled")
+			return
+		}
+
+		fingerprint := store.Fingerprint(root)
+		notes, err := s.Load()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+		idx := search.Build(notes)
+		idx.Fingerprint = fingerprint
+		if err := idx.Save(root); err != nil {
+			fmt.Println("Error writing search index:", err)
+			return
+		}
+		fmt.Printf("Indexed %d note(s)\n", len(notes))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+
+	indexCmd.Flags().BoolVar(&indexOff, "off", false, "Disable and remove the search index")
+}
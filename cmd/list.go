@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,99 +9,189 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/query"
 )
 
 var listFile string
 var listTag string
+var listBranch string
+var listAuthor string
+var listLine int
+var listFormat string
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
-	Use:   "list",
+	Use:   "list [query]",
 	Short: "List your saved notes",
-	Long:  `Lists all notes saved for the current project.`,
+	Long: `Lists all notes saved for the current project.
+
+An optional query narrows the list down, e.g.
+
+  notes list 'tag:bug AND file:src/**/*.go'
+  notes list 'NOT tag:done (author:alice OR created:>2025-01-01)'
+
+Terms are free text, "quoted phrases", #tag, or one of the fields tag:,
+file:, line:, created:, author:, branch:, message: and id:. They are
+combined with AND (the default), OR, NOT and parentheses.
+
+Use --format to print notes for scripts instead of people:
+
+  json      a JSON array of notes
+  jsonl     one JSON object per line
+  csv, tsv  a header row followed by one row per note
+  table     aligned columns
+  text      the default human-readable output
+
+Any other value containing "{{" is used as a Go text/template executed for
+each note, e.g. --format '{{.ID}} {{.File}}:{{.Line}}'. See the README for
+the fields of the JSON schema, which templates share.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		root, err := os.Getwd()
+		q, err := query.Parse(strings.Join(args, " "))
 		if err != nil {
-			fmt.Println("Error getting root directory: ", err)
-			return
-		}
-
-		notesPath := filepath.Join(root, ".notes", "notes.json")
-		if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-			fmt.Println("No notes found")
+			fmt.Println("Error:", err)
 			return
 		}
 
-		data, err := os.ReadFile(notesPath)
+		s, err := openStore()
 		if err != nil {
-			fmt.Println("Error reading notes file: ", err)
+			fmt.Println("Error opening notes: ", err)
 			return
 		}
 
-		var notes []Note
-		if err := json.Unmarshal(data, &notes); err != nil {
-			fmt.Println("Error unmarshalling notes: ", err)
+		notes, err := s.Load()
+		if err != nil {
+			fmt.Println("Error reading notes: ", err)
 			return
 		}
 
-		if len(notes) == 0 {
+		structured := listFormat != "" && listFormat != "text"
+		if len(notes) == 0 && !structured {
 			fmt.Println("No notes found")
 			return
 		}
 
-		for _, n := range notes {
+		wantFile := resolveFile(s.Root(), listFile)
+		locator := anchor.NewLocator(s.Root())
+		var matched []locatedNote
+		for _, stored := range notes {
+			n, status := locate(locator, stored)
 
 			if listFile != "" {
 				noteBase := filepath.Base(n.File)
-				inputBase := filepath.Base(listFile)
+				inputBase := filepath.Base(wantFile)
 
-				if n.File != listFile && noteBase != inputBase {
+				if n.File != wantFile && noteBase != inputBase {
 					continue
 				}
 			}
 
-			if listTag != "" {
-				found := false
-				for _, tag := range n.Tags {
-					if tag == listTag {
-						found = true
-						break
-					}
-				}
-				if !found {
-					continue
-				}
+			if listTag != "" && !n.HasTag(listTag) {
+				continue
 			}
 
-			id := color.New(color.FgHiCyan).Sprint(n.ID[:8])
-			timestamp := color.New(color.FgHiBlack).Sprint(n.CreatedAt.Format(time.RFC822)) // 30 May 25 12:00 PM
-			message := color.New(color.FgWhite).Sprint(n.Message)
+			if listBranch != "" && n.Branch != listBranch {
+				continue
+			}
 
-			location := ""
-			if n.File != "" {
-				location = fmt.Sprintf(" → %s", n.File)
-				if n.Line > 0 {
-					location += fmt.Sprintf(":%d", n.Line)
-				}
+			if listAuthor != "" && !strings.Contains(strings.ToLower(n.Author), strings.ToLower(listAuthor)) {
+				continue
 			}
 
-			fmt.Printf("[%s] %s%s\n", id, message, location)
-			if len(n.Tags) > 0 {
-				tagStr := color.New(color.FgGreen).SprintFunc()
-				coloredTags := make([]string, len(n.Tags))
-				for i, tag := range n.Tags {
-					coloredTags[i] = tagStr(tag)
-				}
-				fmt.Printf("    Tags: %s\n", strings.Join(coloredTags, ", "))
+			if listLine > 0 && !n.Covers(listLine) {
+				continue
 			}
-			fmt.Printf("    %s\n\n", timestamp)
+
+			if !q.Match(n) {
+				continue
+			}
+
+			matched = append(matched, locatedNote{Note: n, Status: status, StoredLine: stored.Line})
+		}
+
+		if structured {
+			if err := writeNotes(os.Stdout, listFormat, matched); err != nil {
+				fmt.Println("Error formatting notes:", err)
+				return
+			}
+			return
+		}
+
+		for _, n := range matched {
+			printNote(n)
 		}
 	},
 }
 
+// printNote prints a note in the human-readable list format.
+func printNote(n locatedNote) {
+	id := color.New(color.FgHiCyan).Sprint(n.ShortID())
+	timestamp := color.New(color.FgHiBlack).Sprint(n.CreatedAt.Format(time.RFC822)) // 30 May 25 12:00 PM
+	first, rest, _ := strings.Cut(n.Message, "\n")
+	message := color.New(color.FgWhite).Sprint(first)
+
+	location := ""
+	if n.File != "" {
+		location = fmt.Sprintf(" → %s", n.Location())
+		switch n.Status {
+		case anchor.StatusMoved:
+			location += color.YellowString(" (moved from line %d)", n.StoredLine)
+		case anchor.StatusOrphaned:
+			location += color.RedString(" (orphaned)")
+		}
+	}
+
+	fmt.Printf("[%s] %s%s\n", id, message, location)
+	if rest != "" {
+		fmt.Println(color.New(color.FgWhite).Sprint(indentLines(rest)))
+	}
+	if len(n.Tags) > 0 {
+		tagStr := color.New(color.FgGreen).SprintFunc()
+		coloredTags := make([]string, len(n.Tags))
+		for i, tag := range n.Tags {
+			coloredTags[i] = tagStr(tag)
+		}
+		fmt.Printf("    Tags: %s\n", strings.Join(coloredTags, ", "))
+	}
+	if git := gitSummary(n.Note); git != "" {
+		fmt.Printf("    Git: %s\n", color.New(color.FgMagenta).Sprint(git))
+	}
+	fmt.Printf("    %s\n\n", timestamp)
+}
+
+// indentLines indents every line of text, so the body of a multi-line
+// message reads as part of one entry in a list.
+func indentLines(text string) string {
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
+
+// gitSummary describes the git context of a note as "branch @ sha by name".
+func gitSummary(n Note) string {
+	var parts []string
+	if n.Branch != "" {
+		parts = append(parts, n.Branch)
+	}
+	if n.Commit != "" {
+		if len(parts) > 0 {
+			parts = append(parts, "@")
+		}
+		parts = append(parts, shortSHA(n.Commit))
+	}
+	if n.Author != "" {
+		parts = append(parts, "by", gitinfo.AuthorName(n.Author))
+	}
+	return strings.Join(parts, " ")
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 
 	listCmd.Flags().StringVarP(&listFile, "file", "f", "", "Optional file to filter notes by")
+	listCmd.Flags().StringVar(&listFormat, "format", "text", "Output format: text, json, jsonl, csv, tsv, table or a Go template")
+	listCmd.Flags().IntVarP(&listLine, "line", "l", 0, "Optional line; only notes whose lines cover it are listed")
 	listCmd.Flags().StringVarP(&listTag, "tag", "t", "", "Optional tag to filter notes by")
+	listCmd.Flags().StringVarP(&listBranch, "branch", "b", "", "Optional git branch to filter notes by")
+	listCmd.Flags().StringVarP(&listAuthor, "author", "a", "", "Optional author name or email to filter notes by")
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/lsp"
+)
+
+// lspCmd represents the lsp command
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server showing notes in your editor",
+	Long: `Runs a Language Server Protocol server on stdin and stdout, so any editor
+with LSP support shows the project's notes:
+
+  - every note is an information diagnostic and a code lens on its lines
+  - hovering a noted line shows the full notes with their tags
+  - code actions add a note at the cursor, or edit or delete the notes there
+
+Adding or editing a note opens it in a new editor tab with the same front
+matter as notes add --editor; saving the tab saves the note. Changes made
+outside the editor, e.g. by notes add or a git pull, are picked up within a
+second.
+
+Configure your editor to start "notes lsp" for all files of the project.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// stdout carries the protocol, so errors go to stderr
+		if err := newLSPServer(lsp.NewConn(os.Stdin, os.Stdout)).run(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error running language server:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,787 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/lsp"
+	"spjoes/notes/search"
+	"spjoes/notes/store"
+)
+
+// Commands the server offers through code lenses and code actions.
+const (
+	lspAddCommand    = "notes.add"
+	lspEditCommand   = "notes.edit"
+	lspDeleteCommand = "notes.delete"
+)
+
+// lspPollInterval is how often the server checks the store for changes made
+// outside it, e.g. by notes add or a git pull.
+const lspPollInterval = time.Second
+
+// lspDraft is a note being written in the editor. Saving the draft's file
+// stores the note.
+type lspDraft struct {
+	// id is the note being edited, or empty for a new note.
+	id   string
+	base composed
+	// failed is set when the last save could not be read back, so the file
+	// is kept for the user.
+	failed bool
+}
+
+// lspServer serves the notes of a project over the Language Server
+// Protocol.
+type lspServer struct {
+	conn *lsp.Conn
+
+	mu          sync.Mutex
+	root        string
+	store       store.NoteStore
+	notes       []Note
+	fingerprint uint64
+	// docs holds the text of the open documents by project file.
+	docs map[string][]string
+	// published are the files with diagnostics on the client.
+	published map[string]bool
+	drafts    map[string]*lspDraft
+	draftDir  string
+	// pending holds callbacks for the responses to our requests, by ID.
+	pending map[string]func(*lsp.Message)
+
+	showDocument bool
+	lensRefresh  bool
+	shutdown     bool
+	done         chan struct{}
+}
+
+func newLSPServer(conn *lsp.Conn) *lspServer {
+	return &lspServer{
+		conn:      conn,
+		docs:      map[string][]string{},
+		published: map[string]bool{},
+		drafts:    map[string]*lspDraft{},
+		pending:   map[string]func(*lsp.Message){},
+		done:      make(chan struct{}),
+	}
+}
+
+// run serves requests until the client exits or closes the stream.
+func (s *lspServer) run() error {
+	defer close(s.done)
+	defer s.removeDrafts()
+	for {
+		m, err := s.conn.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *lsp.Error
+		if errors.As(err, &rpcErr) {
+			s.conn.ReplyError(nil, rpcErr.Code, rpcErr.Message)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.IsResponse() {
+			s.mu.Lock()
+			callback := s.pending[string(m.ID)]
+			delete(s.pending, string(m.ID))
+			s.mu.Unlock()
+			if callback != nil {
+				callback(m)
+			}
+			continue
+		}
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(m)
+		if m.IsNotification() {
+			if err != nil {
+				s.showMessage(lsp.MessageError, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			if errors.As(err, &rpcErr) {
+				s.conn.ReplyError(m.ID, rpcErr.Code, rpcErr.Message)
+			} else {
+				s.conn.ReplyError(m.ID, lsp.RequestFailed, err.Error())
+			}
+			continue
+		}
+		s.conn.Reply(m.ID, result)
+	}
+}
+
+// handle answers a request or handles a notification.
+func (s *lspServer) handle(m *lsp.Message) (any, error) {
+	if s.store == nil && m.Method != "initialize" {
+		if m.IsNotification() {
+			return nil, nil
+		}
+		return nil, &lsp.Error{Code: -32002, Message: "server not initialized"}
+	}
+
+	switch m.Method {
+	case "initialize":
+		var p lsp.InitializeParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(p)
+
+	case "initialized":
+		s.mu.Lock()
+		s.publishAll()
+		s.mu.Unlock()
+		go s.poll()
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p lsp.DidOpenTextDocumentParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if file, ok := s.file(p.TextDocument.URI); ok {
+			s.docs[file] = splitLines(p.TextDocument.Text)
+			s.publish(file)
+		}
+		return nil, nil
+
+	case "textDocument/didChange":
+		var p lsp.DidChangeTextDocumentParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		file, ok := s.file(p.TextDocument.URI)
+		if ok && len(p.ContentChanges) > 0 {
+			s.docs[file] = splitLines(p.ContentChanges[len(p.ContentChanges)-1].Text)
+			s.publish(file)
+		}
+		return nil, nil
+
+	case "textDocument/didSave":
+		var p lsp.DidSaveTextDocumentParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.saveDraft(p.TextDocument.URI)
+
+	case "textDocument/didClose":
+		var p lsp.DidCloseTextDocumentParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closeDraft(p.TextDocument.URI)
+		if file, ok := s.file(p.TextDocument.URI); ok {
+			delete(s.docs, file)
+			s.publish(file)
+		}
+		return nil, nil
+
+	case "textDocument/hover":
+		var p lsp.TextDocumentPositionParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p), nil
+
+	case "textDocument/codeLens":
+		var p lsp.CodeLensParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.codeLenses(p), nil
+
+	case "textDocument/codeAction":
+		var p lsp.CodeActionParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.codeActions(p), nil
+
+	case "workspace/executeCommand":
+		var p lsp.ExecuteCommandParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.executeCommand(p)
+	}
+
+	if m.IsNotification() {
+		// e.g. $/cancelRequest or workspace/didChangeConfiguration
+		return nil, nil
+	}
+	return nil, &lsp.Error{Code: lsp.MethodNotFound, Message: "method not supported: " + m.Method}
+}
+
+func unmarshalParams(m *lsp.Message, v any) error {
+	if len(m.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &lsp.Error{Code: lsp.InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *lspServer) initialize(p lsp.InitializeParams) (any, error) {
+	root := ""
+	if rootDir == "" && os.Getenv("NOTES_ROOT") == "" {
+		if p.RootURI != "" {
+			root, _ = lsp.URIToPath(p.RootURI)
+		} else {
+			root = p.RootPath
+		}
+	}
+	var err error
+	if root != "" {
+		root, err = store.FindRoot(root)
+	} else {
+		root, err = projectRoot()
+	}
+	if err != nil {
+		return nil, err
+	}
+	st, err := search.Open(root)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = st
+	s.root = st.Root()
+	s.showDocument = p.Capabilities.Window.ShowDocument.Support
+	s.lensRefresh = p.Capabilities.Workspace.CodeLens.RefreshSupport
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			// full text on every change, which keeps notes in place while
+			// lines are inserted above them
+			"textDocumentSync":   map[string]any{"openClose": true, "change": 1, "save": true},
+			"hoverProvider":      true,
+			"codeLensProvider":   map[string]any{"resolveProvider": false},
+			"codeActionProvider": true,
+			"executeCommandProvider": map[string]any{
+				"commands": []string{lspAddCommand, lspEditCommand, lspDeleteCommand},
+			},
+		},
+		"serverInfo": map[string]any{"name": "notes"},
+	}, nil
+}
+
+// reload reads the notes from the store. The caller holds s.mu.
+func (s *lspServer) reload() error {
+	notes, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+	s.notes = notes
+	s.fingerprint = storeFingerprint(s.root)
+	return nil
+}
+
+// poll reloads the notes when the store changes until the server stops.
+func (s *lspServer) poll() {
+	ticker := time.NewTicker(lspPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		if storeFingerprint(s.root) != s.fingerprint {
+			s.changed()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// changed reloads the notes and refreshes what the client shows of them.
+// The caller holds s.mu.
+func (s *lspServer) changed() {
+	if err := s.reload(); err != nil {
+		s.showMessage(lsp.MessageError, "Cannot read notes: "+err.Error())
+		return
+	}
+	s.publishAll()
+	if s.lensRefresh {
+		s.request("workspace/codeLens/refresh", nil, nil)
+	}
+}
+
+// file returns the project file of a document URI.
+func (s *lspServer) file(uri string) (string, bool) {
+	path, err := lsp.URIToPath(uri)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// uri returns the URI of a project file.
+func (s *lspServer) uri(file string) string {
+	return lsp.PathToURI(filepath.Join(s.root, filepath.FromSlash(file)))
+}
+
+// located returns the notes of file at their current lines, following the
+// unsaved text of open documents. The caller holds s.mu.
+func (s *lspServer) located(file string) []locatedNote {
+	locator := anchor.NewLocator(s.root)
+	if lines, ok := s.docs[file]; ok {
+		locator.SetLines(file, lines)
+	}
+	var notes []locatedNote
+	for _, stored := range s.notes {
+		if stored.File != file || stored.Line <= 0 {
+			continue
+		}
+		n, status := locate(locator, stored)
+		notes = append(notes, locatedNote{Note: n, Status: status, StoredLine: stored.Line})
+	}
+	return notes
+}
+
+// lines returns the text of a project file, from the editor when it is open.
+func (s *lspServer) lines(file string) []string {
+	if lines, ok := s.docs[file]; ok {
+		return lines
+	}
+	lines, _ := anchor.ReadLines(filepath.Join(s.root, filepath.FromSlash(file)))
+	return lines
+}
+
+// noteRange returns the range of the lines a note covers.
+func noteRange(n Note, lines []string) lsp.Range {
+	first := n.Line - 1
+	last := max(n.LastLine()-1, first)
+	end := 0
+	if last < len(lines) {
+		end = lsp.UTF16Len(lines[last])
+	}
+	return lsp.Range{
+		Start: lsp.Position{Line: first},
+		End:   lsp.Position{Line: last, Character: end},
+	}
+}
+
+// publishAll publishes the diagnostics of every file with notes, and clears
+// those of files that no longer have any. The caller holds s.mu.
+func (s *lspServer) publishAll() {
+	files := map[string]bool{}
+	for _, n := range s.notes {
+		if n.File != "" && n.Line > 0 {
+			files[n.File] = true
+		}
+	}
+	for file := range s.published {
+		files[file] = true
+	}
+	for file := range files {
+		s.publish(file)
+	}
+}
+
+// publish sends the notes of a file as information diagnostics. The caller
+// holds s.mu.
+func (s *lspServer) publish(file string) {
+	notes := s.located(file)
+	if len(notes) == 0 && !s.published[file] {
+		return
+	}
+
+	lines := s.lines(file)
+	diagnostics := []lsp.Diagnostic{}
+	for _, n := range notes {
+		message := n.Message
+		if len(n.Tags) > 0 {
+			message += "\n[" + strings.Join(n.Tags, ", ") + "]"
+		}
+		if n.Status == anchor.StatusOrphaned {
+			message = "(orphaned) " + message
+		}
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    noteRange(n.Note, lines),
+			Severity: lsp.SeverityInformation,
+			Code:     n.ShortID(),
+			Source:   "notes",
+			Message:  message,
+		})
+	}
+	s.conn.Notify("textDocument/publishDiagnostics", lsp.PublishDiagnosticsParams{URI: s.uri(file), Diagnostics: diagnostics})
+	s.published[file] = len(notes) > 0
+}
+
+// notesAt returns the notes covering a zero-based line of a document.
+func (s *lspServer) notesAt(uri string, line int) []locatedNote {
+	file, ok := s.file(uri)
+	if !ok {
+		return nil
+	}
+	var at []locatedNote
+	for _, n := range s.located(file) {
+		if n.Covers(line + 1) {
+			at = append(at, n)
+		}
+	}
+	sort.SliceStable(at, func(i, j int) bool { return at[i].CreatedAt.Before(at[j].CreatedAt) })
+	return at
+}
+
+func (s *lspServer) hover(p lsp.TextDocumentPositionParams) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notes := s.notesAt(p.TextDocument.URI, p.Position.Line)
+	if len(notes) == 0 {
+		return nil
+	}
+
+	var parts []string
+	for _, n := range notes {
+		header := []string{"**" + n.ShortID() + "**"}
+		for _, t := range n.Tags {
+			header = append(header, "`"+t+"`")
+		}
+		if n.Author != "" {
+			header = append(header, gitinfo.AuthorName(n.Author))
+		}
+		if !n.CreatedAt.IsZero() {
+			header = append(header, n.CreatedAt.Local().Format("2006-01-02"))
+		}
+		if n.Status == anchor.StatusOrphaned {
+			header = append(header, "*orphaned*")
+		}
+		parts = append(parts, strings.Join(header, " · ")+"\n\n"+n.Message)
+	}
+	r := noteRange(notes[0].Note, s.lines(notes[0].File))
+	return lsp.Hover{
+		Contents: lsp.MarkupContent{Kind: "markdown", Value: strings.Join(parts, "\n\n---\n\n")},
+		Range:    &r,
+	}
+}
+
+func (s *lspServer) codeLenses(p lsp.CodeLensParams) []lsp.CodeLens {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.file(p.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+	lines := s.lines(file)
+	lenses := []lsp.CodeLens{}
+	for _, n := range s.located(file) {
+		title := firstLine(n.Message)
+		if len([]rune(title)) > 60 {
+			title = string([]rune(title)[:59]) + "…"
+		}
+		lenses = append(lenses, lsp.CodeLens{
+			Range:   noteRange(n.Note, lines),
+			Command: &lsp.Command{Title: "📝 " + title, Command: lspEditCommand, Arguments: []any{n.ID}},
+		})
+	}
+	return lenses
+}
+
+func (s *lspServer) codeActions(p lsp.CodeActionParams) []lsp.CodeAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.file(p.TextDocument.URI); !ok {
+		return nil
+	}
+
+	first, last := p.Range.Start.Line+1, p.Range.End.Line+1
+	// a selection ending at the start of a line doesn't include it
+	if last > first && p.Range.End.Character == 0 {
+		last--
+	}
+	title := fmt.Sprintf("Add a note on line %d", first)
+	if last > first {
+		title = fmt.Sprintf("Add a note on lines %d-%d", first, last)
+	}
+	actions := []lsp.CodeAction{{
+		Title:   title,
+		Kind:    "quickfix",
+		Command: &lsp.Command{Title: title, Command: lspAddCommand, Arguments: []any{p.TextDocument.URI, first, last}},
+	}}
+	for _, n := range s.notesAt(p.TextDocument.URI, p.Range.Start.Line) {
+		label := firstLine(n.Message)
+		if len([]rune(label)) > 40 {
+			label = string([]rune(label)[:39]) + "…"
+		}
+		actions = append(actions,
+			lsp.CodeAction{
+				Title:   fmt.Sprintf("Edit note %q", label),
+				Kind:    "quickfix",
+				Command: &lsp.Command{Title: "Edit note", Command: lspEditCommand, Arguments: []any{n.ID}},
+			},
+			lsp.CodeAction{
+				Title:   fmt.Sprintf("Delete note %q", label),
+				Kind:    "quickfix",
+				Command: &lsp.Command{Title: "Delete note", Command: lspDeleteCommand, Arguments: []any{n.ID}},
+			})
+	}
+	return actions
+}
+
+func (s *lspServer) executeCommand(p lsp.ExecuteCommandParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch p.Command {
+	case lspAddCommand:
+		var uri string
+		var first, last int
+		if len(p.Arguments) < 2 || json.Unmarshal(p.Arguments[0], &uri) != nil || json.Unmarshal(p.Arguments[1], &first) != nil {
+			return &lsp.Error{Code: lsp.InvalidParams, Message: "expected a document URI and a line"}
+		}
+		if len(p.Arguments) > 2 {
+			json.Unmarshal(p.Arguments[2], &last)
+		}
+		file, ok := s.file(uri)
+		if !ok {
+			return fmt.Errorf("%s is not in the project %s", uri, s.root)
+		}
+		span := store.Span{Line: first}
+		if last > first {
+			span.EndLine = last
+		}
+		return s.openDraft("", composed{File: file, Span: span})
+
+	case lspEditCommand, lspDeleteCommand:
+		var id string
+		if len(p.Arguments) < 1 || json.Unmarshal(p.Arguments[0], &id) != nil {
+			return &lsp.Error{Code: lsp.InvalidParams, Message: "expected a note ID"}
+		}
+		n, err := s.store.Get(id)
+		if err != nil {
+			return err
+		}
+		if p.Command == lspEditCommand {
+			return s.openDraft(n.ID, composed{Message: n.Message, File: n.File, Span: n.Span(), Tags: n.Tags})
+		}
+		s.confirmDelete(n)
+		return nil
+	}
+	return &lsp.Error{Code: lsp.InvalidParams, Message: "unknown command " + p.Command}
+}
+
+// confirmDelete asks the user before deleting a note. The caller holds s.mu.
+func (s *lspServer) confirmDelete(n Note) {
+	params := lsp.ShowMessageRequestParams{
+		Type:    lsp.MessageWarning,
+		Message: fmt.Sprintf("Delete note %s %q?", n.ShortID(), firstLine(n.Message)),
+		Actions: []lsp.MessageActionItem{{Title: "Delete"}, {Title: "Cancel"}},
+	}
+	s.request("window/showMessageRequest", params, func(m *lsp.Message) {
+		var choice *lsp.MessageActionItem
+		if m.Error != nil || json.Unmarshal(m.Result, &choice) != nil || choice == nil || choice.Title != "Delete" {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.store.Delete(n.ID); err != nil {
+			s.showMessage(lsp.MessageError, "Cannot delete note: "+err.Error())
+			return
+		}
+		s.showMessage(lsp.MessageInfo, "Deleted note "+n.ShortID())
+		s.changed()
+	})
+}
+
+// openDraft writes a note to a file in the editor, where saving it stores
+// the note. The caller holds s.mu.
+func (s *lspServer) openDraft(id string, c composed) error {
+	if s.draftDir == "" {
+		dir, err := os.MkdirTemp("", "notes-lsp-")
+		if err != nil {
+			return err
+		}
+		s.draftDir = dir
+	}
+	f, err := os.CreateTemp(s.draftDir, "NOTE_EDITMSG-*.md")
+	if err != nil {
+		return err
+	}
+	tmpl := composeTemplate(c)
+	_, err = f.WriteString(tmpl)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	s.drafts[f.Name()] = &lspDraft{id: id, base: c}
+
+	if !s.showDocument {
+		s.showMessage(lsp.MessageInfo, "Write the note in "+f.Name()+" and save it")
+		return nil
+	}
+	line := messageLine(tmpl) - 1
+	s.request("window/showDocument", lsp.ShowDocumentParams{
+		URI:       lsp.PathToURI(f.Name()),
+		TakeFocus: true,
+		Selection: &lsp.Range{Start: lsp.Position{Line: line}, End: lsp.Position{Line: line}},
+	}, nil)
+	return nil
+}
+
+// draft returns the draft open in a document.
+func (s *lspServer) draft(uri string) (string, *lspDraft) {
+	path, err := lsp.URIToPath(uri)
+	if err != nil {
+		return "", nil
+	}
+	return path, s.drafts[path]
+}
+
+// saveDraft stores the note of a saved draft.
+func (s *lspServer) saveDraft(uri string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, d := s.draft(uri)
+	if d == nil {
+		return nil
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c, err := parseComposed(string(text), d.base)
+	if errors.Is(err, errEmptyNote) {
+		s.showMessage(lsp.MessageInfo, "Empty note, nothing saved")
+		s.forgetDraft(path)
+		return nil
+	}
+	if err != nil {
+		d.failed = true
+		return fmt.Errorf("cannot save the note: %w", err)
+	}
+	d.failed = false
+	file := store.NormalizePath(s.root, c.File)
+
+	var saved Note
+	if d.id == "" {
+		n := Note{Message: c.Message, Tags: c.Tags}
+		n.SetSpan(c.Span)
+		recordGitContext(s.root, &n)
+		relink(s.root, &n, file)
+		if saved, err = s.store.Add(n); err != nil {
+			return err
+		}
+	} else {
+		if saved, err = s.store.Get(d.id); err != nil {
+			return err
+		}
+		saved.Message, saved.Tags = c.Message, c.Tags
+		if c.File != d.base.File || c.Span != d.base.Span {
+			saved.SetSpan(c.Span)
+			relink(s.root, &saved, file)
+		}
+		if err := s.store.Update(saved); err != nil {
+			return err
+		}
+	}
+
+	// later saves of the same draft edit the note just saved
+	d.id, d.base = saved.ID, c
+	s.showMessage(lsp.MessageInfo, fmt.Sprintf("Saved note %s", saved.ShortID()))
+	s.changed()
+	return nil
+}
+
+// closeDraft discards the draft open in a document, keeping its file if it
+// could not be saved. The caller holds s.mu.
+func (s *lspServer) closeDraft(uri string) {
+	path, d := s.draft(uri)
+	if d == nil {
+		return
+	}
+	if d.failed {
+		delete(s.drafts, path)
+		s.showMessage(lsp.MessageWarning, "The note was not saved; your text is in "+path)
+		return
+	}
+	s.forgetDraft(path)
+}
+
+// forgetDraft removes a draft and its file.
+func (s *lspServer) forgetDraft(path string) {
+	delete(s.drafts, path)
+	os.Remove(path)
+}
+
+// removeDrafts removes the files of the drafts left when the server stops,
+// except those that could not be saved.
+func (s *lspServer) removeDrafts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path, d := range s.drafts {
+		if !d.failed {
+			s.forgetDraft(path)
+		}
+	}
+	if s.draftDir != "" {
+		// only removed when empty
+		os.Remove(s.draftDir)
+	}
+}
+
+// request sends a request to the client, calling callback with the
+// response. The caller holds s.mu.
+func (s *lspServer) request(method string, params any, callback func(*lsp.Message)) {
+	id, err := s.conn.Request(method, params)
+	if err == nil && callback != nil {
+		s.pending[id] = callback
+	}
+}
+
+func (s *lspServer) showMessage(kind int, message string) {
+	s.conn.Notify("window/showMessage", lsp.ShowMessageParams{Type: kind, Message: message})
+}
+
+// splitLines splits the text of a document into lines like
+// anchor.ReadLines.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
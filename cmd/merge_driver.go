@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/store"
+)
+
+// mergeDriverCmd represents the merge-driver command
+var mergeDriverCmd = &cobra.Command{
+	Use:   "merge-driver <base> <ours> <theirs>",
+	Short: "Three-way merge of notes.json files, for use as a git merge driver",
+	Long: `Merges two versions of notes.json against their common ancestor, keyed
+by note ID. Notes added on either side are kept, deletions are honoured and
+edits are merged field by field. The result is written over <ours>.
+
+Git calls this as "notes merge-driver %O %A %B" once it is installed with
+"notes git install-merge-driver". When both sides changed the same field
+differently, our version is kept and the command exits non-zero so git
+reports the file as conflicted.`,
+	Args:          cobra.ExactArgs(3),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		base, err := readNotesFile(args[0])
+		if err != nil {
+			return mergeDriverError(err)
+		}
+		ours, err := readNotesFile(args[1])
+		if err != nil {
+			return mergeDriverError(err)
+		}
+		theirs, err := readNotesFile(args[2])
+		if err != nil {
+			return mergeDriverError(err)
+		}
+
+		merged, conflicts := store.Merge3(base, ours, theirs)
+
+		data, err := store.EncodeNotes(merged)
+		if err != nil {
+			return mergeDriverError(err)
+		}
+		if err := os.WriteFile(args[1], data, 0644); err != nil {
+			return mergeDriverError(err)
+		}
+
+		if len(conflicts) > 0 {
+			for _, c := range conflicts {
+				if c.Field == "deleted" {
+					fmt.Fprintf(os.Stderr, "notes: note %s was deleted on one side and changed on the other; kept it\n", c.NoteID)
+				} else {
+					fmt.Fprintf(os.Stderr, "notes: note %s has conflicting changes to %q; kept ours\n", c.NoteID, c.Field)
+				}
+			}
+			return fmt.Errorf("%d conflicting change(s)", len(conflicts))
+		}
+		return nil
+	},
+}
+
+func mergeDriverError(err error) error {
+	fmt.Fprintln(os.Stderr, "notes merge-driver:", err)
+	return err
+}
+
+// readNotesFile reads a notes.json version handed to the merge driver. A
+// missing or empty file, as git passes when there is no common ancestor, is
+// an empty list.
+func readNotesFile(path string) ([]Note, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return []Note{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	notes, err := store.DecodeNotes(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return notes, nil
+}
+
+func init() {
+	rootCmd.AddCommand(mergeDriverCmd)
+}
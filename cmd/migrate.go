@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/store"
+)
+
+var migrateTo string
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Convert the notes store to another storage format",
+	Long: `Converts the notes store of the current project to another storage format.
+
+  json      every note in a single .notes/notes.json array (default)
+  per-file  each note in its own .notes/notes/<id>.json file, so notes added
+            on different branches merge without conflicts
+
+The selected format is recorded in .notes/config.json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := projectRoot()
+		if err != nil {
+			fmt.Println("Error finding project root:", err)
+			return
+		}
+
+		n, err := store.Migrate(root, migrateTo)
+		if err != nil {
+			fmt.Println("Error migrating notes:", err)
+			return
+		}
+
+		fmt.Printf("Migrated %d note(s) to %s storage.\n", n, migrateTo)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "Storage format to convert to (json or per-file)")
+	migrateCmd.MarkFlagRequired("to")
+}
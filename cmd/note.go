@@ -1,75 +1,158 @@
 package cmd
 
 import (
-	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"time"
 
-	"github.com/google/uuid"
+	"spjoes/notes/anchor"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/search"
+	"spjoes/notes/store"
 )
 
-type Note struct {
-	ID        string    `json:"id"`
-	Message   string    `json:"message"`
-	File      string    `json:"file,omitempty"`
-	Line      int       `json:"line,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	Tags      []string  `json:"tags,omitempty"`
-}
+type Note = store.Note
+
+// projectRoot returns the root of the current project: the --root flag,
+// then $NOTES_ROOT, then the nearest parent directory with .notes or .git.
+func projectRoot() (string, error) {
+	if rootDir != "" {
+		return filepath.Abs(rootDir)
+	}
+	if env := os.Getenv("NOTES_ROOT"); env != "" {
+		return filepath.Abs(env)
+	}
 
-func SaveNote(message string, file string, line int, tags []string) error {
 	//get the project root folder
-	root, err := os.Getwd()
+	cwd, err := os.Getwd()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return store.FindRoot(cwd)
+}
 
-	if file != "" {
-		if relPath, err := filepath.Rel(root, file); err == nil {
-			file = relPath
+// openStore returns the note store for the current project.
+func openStore() (store.NoteStore, error) {
+	root, err := projectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return search.Open(root)
+}
+
+// storeFingerprint summarizes the names, sizes and modification times of the
+// files in the .notes directory of root, to notice changes made by other
+// processes.
+func storeFingerprint(root string) uint64 {
+	h := fnv.New64a()
+	dir := filepath.Join(root, ".notes")
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == "index" {
+			return filepath.SkipDir
 		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return h.Sum64()
+}
+
+// resolveFile turns a file given on the command line into a path relative to
+// root. Paths are taken relative to the working directory, unless only the
+// root-relative path exists.
+func resolveFile(root, file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return store.NormalizePath(root, file)
 	}
 
-	note := Note{
-		ID:        uuid.New().String(),
-		Message:   message,
-		File:      file,
-		Line:      line,
-		CreatedAt: time.Now(),
-		Tags:      tags,
+	cwd, err := os.Getwd()
+	if err != nil {
+		return store.NormalizePath(root, file)
 	}
 
-	//create the notes directory if it doesn't exist
-	notesDir := filepath.Join(root, ".notes")
-	if err := os.MkdirAll(notesDir, 0755); err != nil {
-		return err
+	abs := filepath.Join(cwd, file)
+	if _, err := os.Stat(abs); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			abs = filepath.Join(root, file)
+		}
 	}
+	return store.NormalizePath(root, abs)
+}
 
-	if err := hideFile(notesDir); err != nil {
+func SaveNote(message string, file string, span store.Span, tags []string, noGit bool) error {
+	s, err := openStore()
+	if err != nil {
 		return err
 	}
 
-	//read existing notes
-	notesPath := filepath.Join(notesDir, "notes.json")
-	var notes []Note
+	note := Note{
+		Message: message,
+		Tags:    tags,
+	}
+	note.SetSpan(span)
+	relink(s.Root(), &note, resolveFile(s.Root(), file))
+	if noGit {
+		note.Base = ""
+	} else {
+		recordGitContext(s.Root(), &note)
+	}
+	_, err = s.Add(note)
+	return err
+}
 
-	if _, err := os.Stat(notesPath); err == nil {
-		data, err := os.ReadFile(notesPath)
-		if err != nil {
-			return err
+// recordGitContext stores the commit, branch and author the note is written
+// in. Outside a git repository the note is left unchanged.
+func recordGitContext(root string, note *Note) {
+	ctx, err := gitinfo.Current(root)
+	if err != nil {
+		return
+	}
+	note.Commit = ctx.Commit
+	note.Branch = ctx.Branch
+	note.Author = ctx.Author
+}
+
+// relink points note at file, a path relative to root, capturing the anchor
+// of its line and the current commit as its base, so the note can follow the
+// code later. The commit the note was written at is kept.
+func relink(root string, note *Note, file string) {
+	note.File = file
+	note.Anchor = anchorFor(root, file, note.Line)
+	note.Base = ""
+	if file != "" {
+		if head, err := gitinfo.Head(root); err == nil {
+			note.Base = head
 		}
-		json.Unmarshal(data, &notes)
 	}
+}
 
-	//Append the new note
-	notes = append(notes, note)
+// locate resolves the note's lines against the current contents of its
+// file, shifting the whole span when the anchored code moved.
+func locate(l *anchor.Locator, n Note) (Note, anchor.Status) {
+	if n.Line <= 0 {
+		return n, anchor.StatusUnchanged
+	}
+	res := l.Locate(n.File, n.Anchor, n.Line)
+	n.ShiftLines(res.Line)
+	return n, res.Status
+}
 
-	//Save the data back to the JSON file
-	updatedData, err := json.MarshalIndent(notes, "", "  ")
+// anchorFor captures the anchor of a line of a project file, so the note can
+// follow the code when lines are inserted above it. It returns nil when
+// there is no line or the file cannot be read.
+func anchorFor(root, file string, line int) *anchor.Anchor {
+	if file == "" || line <= 0 {
+		return nil
+	}
+	a, err := anchor.CaptureFile(filepath.Join(root, filepath.FromSlash(file)), line)
 	if err != nil {
-		return err
+		return nil
 	}
-
-	return os.WriteFile(notesPath, updatedData, 0644)
+	return a
 }
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/config"
+	"spjoes/notes/editor"
+	"spjoes/notes/store"
+)
+
+// openCmd represents the open command
+var openCmd = &cobra.Command{
+	Use:   "open <id>",
+	Short: "Open the file of a note in your editor at the note's line",
+	Long: `Opens the file a note is linked to in your editor, at the note's line.
+
+The editor is the "editor" setting of the user config file, then $VISUAL,
+then $EDITOR. Known editors (vim, neovim, emacs, nano, micro, helix, VS Code,
+Sublime Text, Zed, JetBrains IDEs and more) are opened at the line; the
+"editors" setting adds or overrides argument templates, e.g.
+
+  {"editors": {"vim": "+{line} {file}"}}
+
+You must supply the note ID (first 8 chars or full).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+
+		stored, err := s.Get(args[0])
+		if errors.Is(err, store.ErrNotFound) {
+			fmt.Printf("No note found with ID %s\n", args[0])
+			return
+		}
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		n, _ := locate(anchor.NewLocator(s.Root()), stored)
+		c, err := editorCommand(s.Root(), n)
+		if err != nil {
+			fmt.Println("Error opening editor:", err)
+			return
+		}
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Println("Error running editor:", err)
+		}
+	},
+}
+
+// editorCommand returns the command that opens the file of a note in the
+// user's editor at the note's line.
+func editorCommand(root string, n Note) (*exec.Cmd, error) {
+	if n.File == "" {
+		return nil, fmt.Errorf("note %s is not linked to a file", n.ShortID())
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(root, filepath.FromSlash(n.File))
+	return editor.Command(editor.Find(cfg.Editor), cfg.Editors, path, n.Line, n.Column)
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/store"
+)
+
+const reanchorHookMarker = "# added by notes reanchor --install-hook"
+
+var reanchorDryRun bool
+var reanchorQuiet bool
+var reanchorInstallHook bool
+
+// reanchorCmd represents the reanchor command
+var reanchorCmd = &cobra.Command{
+	Use:   "reanchor",
+	Short: "Follow files renamed or changed in git since notes were created",
+	Long: `Uses git rename detection between the commit each note was linked to its
+file at and HEAD to rewrite the note's file, and the diff hunks in between to
+move its line. Every rewritten note is reported and records HEAD as its new
+base; the commit it was written at is kept.
+
+With --install-hook, post-checkout and post-merge hooks are installed that
+run "notes reanchor --quiet" automatically.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		root := s.Root()
+
+		if reanchorInstallHook {
+			if err := installReanchorHooks(root); err != nil {
+				fmt.Println("Error installing hooks:", err)
+			}
+			return
+		}
+
+		head, err := gitinfo.Head(root)
+		if errors.Is(err, gitinfo.ErrNotRepository) {
+			if !reanchorQuiet {
+				fmt.Println("Not a git repository; nothing to reanchor")
+			}
+			return
+		}
+		if err != nil {
+			fmt.Println("Error reading HEAD:", err)
+			return
+		}
+		prefix, err := gitinfo.Prefix(root)
+		if err != nil {
+			fmt.Println("Error reading repository layout:", err)
+			return
+		}
+
+		notes, err := s.Load()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		renames := map[string]map[string]string{}
+		// moved notes are written at once, so the run takes a single backup
+		var batch store.Batch
+		noCommit := 0
+		for _, n := range notes {
+			base := n.ReanchorBase()
+			if n.File != "" && base == "" {
+				noCommit++
+				continue
+			}
+			if n.File == "" || base == head {
+				continue
+			}
+
+			if _, ok := renames[base]; !ok {
+				r, err := gitinfo.Renames(root, base, head)
+				if err != nil {
+					if !reanchorQuiet {
+						fmt.Printf("%s: cannot compare %s with HEAD: %v\n", n.ShortID(), shortSHA(base), err)
+					}
+					r = nil
+				}
+				renames[base] = r
+			}
+			r := renames[base]
+			if r == nil {
+				continue
+			}
+
+			oldPath := prefix + n.File
+			newPath := oldPath
+			if to, ok := r[oldPath]; ok {
+				if to == "" {
+					if !reanchorQuiet {
+						fmt.Printf("%s: %s was deleted\n", n.ShortID(), n.File)
+					}
+					continue
+				}
+				newPath = to
+			}
+			if !strings.HasPrefix(newPath, prefix) {
+				if !reanchorQuiet {
+					fmt.Printf("%s: %s moved outside the project to %s\n", n.ShortID(), n.File, newPath)
+				}
+				continue
+			}
+
+			updated := n
+			updated.File = strings.TrimPrefix(newPath, prefix)
+			if n.Line > 0 {
+				hunks, err := gitinfo.Hunks(root, base, head, oldPath, newPath)
+				if err == nil {
+					updated.Line, _ = gitinfo.MapLine(hunks, n.Line)
+					if n.EndLine > 0 {
+						updated.EndLine, _ = gitinfo.MapLine(hunks, n.EndLine)
+						updated.EndLine = max(updated.EndLine, updated.Line)
+					}
+				}
+			}
+			if updated.File == n.File && updated.Line == n.Line && updated.EndLine == n.EndLine {
+				continue
+			}
+			updated.Base = head
+
+			if !reanchorQuiet {
+				fmt.Printf("%s %s → %s\n", color.HiCyanString(n.ShortID()), n.Location(), updated.Location())
+			}
+			batch.Update = append(batch.Update, updated)
+		}
+
+		changed := len(batch.Update)
+		if changed > 0 && !reanchorDryRun {
+			if _, err := s.Apply(batch); err != nil {
+				fmt.Println("Error writing notes:", err)
+				return
+			}
+		}
+
+		if reanchorQuiet {
+			return
+		}
+		switch {
+		case changed > 0 && reanchorDryRun:
+			fmt.Printf("%d note(s) would be reanchored\n", changed)
+		case changed > 0:
+			fmt.Printf("Reanchored %d note(s)\n", changed)
+		case noCommit == 0:
+			fmt.Println("All notes are up to date")
+		}
+		if noCommit > 0 {
+			fmt.Printf("Skipped %d note(s) with no recorded commit to compare with\n", noCommit)
+		}
+	},
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// installReanchorHooks adds "notes reanchor --quiet" to the post-checkout and
+// post-merge hooks of the repository, keeping any existing hook commands.
+func installReanchorHooks(root string) error {
+	hooksDir, err := gitinfo.HooksDir(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("notes --root %s reanchor --quiet %s\n", shellQuote(root), reanchorHookMarker)
+	for _, name := range []string{"post-checkout", "post-merge"} {
+		path := filepath.Join(hooksDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		content := string(data)
+		if strings.Contains(content, reanchorHookMarker) {
+			fmt.Printf("%s hook already runs notes reanchor\n", name)
+			continue
+		}
+		if content == "" {
+			content = "#!/bin/sh\n"
+		} else if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += line
+
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s hook\n", name)
+	}
+	return nil
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	rootCmd.AddCommand(reanchorCmd)
+
+	reanchorCmd.Flags().BoolVarP(&reanchorDryRun, "dry-run", "n", false, "Report what would change without writing")
+	reanchorCmd.Flags().BoolVarP(&reanchorQuiet, "quiet", "q", false, "Only report errors")
+	reanchorCmd.Flags().BoolVar(&reanchorInstallHook, "install-hook", false, "Run reanchor from the post-checkout and post-merge git hooks")
+}
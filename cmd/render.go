@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/fatih/color"
+
+	"spjoes/notes/highlight"
+	"spjoes/notes/markdown"
+)
+
+// maxRenderWidth keeps rendered notes readable on wide terminals.
+const maxRenderWidth = 100
+
+// styled returns a function colouring text with the attributes.
+func styled(attrs ...color.Attribute) func(string) string {
+	c := color.New(attrs...)
+	return func(s string) string { return c.Sprint(s) }
+}
+
+// terminalTheme renders markdown with the same colours as the rest of the
+// command output.
+var terminalTheme = markdown.Theme{
+	Heading: func(level int, text string) string {
+		if level == 1 {
+			return styled(color.FgHiCyan, color.Bold, color.Underline)(text)
+		}
+		return styled(color.FgHiCyan, color.Bold)(text)
+	},
+	Bold:   styled(color.Bold),
+	Italic: styled(color.Italic),
+	Code:   styled(color.FgHiYellow),
+	Link:   styled(color.FgHiBlue, color.Underline),
+	URL:    styled(color.FgHiBlack),
+	Quote:  styled(color.FgHiBlack),
+	Marker: styled(color.FgHiCyan),
+	Rule:   styled(color.FgHiBlack),
+	Token: func(kind highlight.Kind, text string) string {
+		return colorTokens([]highlight.Token{{Kind: kind, Text: text}})
+	},
+}
+
+// renderMessage renders the markdown of a note message for stdout, leaving
+// margin columns free for the caller's prefix. Output that isn't going to a
+// terminal gets the message as written.
+func renderMessage(message string, raw bool, margin int) string {
+	if raw || !stdoutIsTerminal() {
+		return message
+	}
+	return markdown.Render(message, max(20, terminalWidth()-margin), terminalTheme)
+}
+
+// stdoutIsTerminal reports whether output goes to a terminal rather than a
+// pipe or file.
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+// terminalWidth returns the width to render text at on stdout.
+func terminalWidth() int {
+	w, _, err := term.GetSize(os.Stdout.Fd())
+	if err != nil || w <= 0 {
+		return 80
+	}
+	return min(w, maxRenderWidth)
+}
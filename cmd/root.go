@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
@@ -10,6 +11,12 @@ var rootCmd = &cobra.Command{
 	Use:   "notes",
 	Short: "Simple CLI for managing notes",
 	Long:  `Notes is a simple CLI for managing notes. It allows you to create, view, and delete notes per project.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// colour is already off when stdout isn't a terminal or NO_COLOR is set
+		if noColor {
+			color.NoColor = true
+		}
+	},
 }
 
 func Execute() {
@@ -19,6 +26,11 @@ func Execute() {
 	}
 }
 
+var rootDir string
+var noColor bool
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&rootDir, "root", "", "Project root containing .notes (defaults to $NOTES_ROOT, then the nearest parent with .notes or .git)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable coloured output")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
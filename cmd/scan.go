@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/files"
+	"spjoes/notes/scan"
+	"spjoes/notes/store"
+)
+
+var scanDryRun bool
+var scanMarkers []string
+
+// scanCmd represents the scan command
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Import TODO, FIXME, HACK and NOTE comments as notes",
+	Long: `Walks the project, skipping files ignored by git, and finds comments
+starting with TODO, FIXME, HACK or NOTE, such as "// TODO: handle errors" or
+"# FIXME(ann): slow". Only comments count: the comment syntax of each
+language is known, so markers in strings or prose are left alone.
+
+Every marker becomes a note linked to its line, tagged "scanned" and with
+the marker as a tag ("todo", "fixme", ...). Scanning again updates those
+notes rather than adding them twice: markers that moved are followed, and
+notes whose comment is gone are tagged "resolved".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		root := s.Root()
+
+		var markers []string
+		for _, m := range scanMarkers {
+			if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+				markers = append(markers, m)
+			}
+		}
+		if len(markers) == 0 {
+			fmt.Println("Please give at least one marker")
+			return
+		}
+		scanner := scan.New(markers)
+
+		var items []scan.Item
+		// the lines of every scanned file, to anchor notes without reading
+		// the files again
+		contents := map[string][]string{}
+		err = files.Walk(root, func(rel string) error {
+			if !scan.Supported(rel) {
+				return nil
+			}
+			lines, err := anchor.ReadLines(filepath.Join(root, filepath.FromSlash(rel)))
+			if err != nil {
+				fmt.Printf("Skipping %s: %v\n", rel, err)
+				return nil
+			}
+			contents[rel] = lines
+			items = append(items, scanner.File(rel, lines)...)
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error walking the project:", err)
+			return
+		}
+
+		notes, err := s.Load()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+		changes := scan.Reconcile(notes, items)
+
+		// every note gets the same git context, looked up once
+		var git Note
+		if !scanDryRun && len(changes.Added)+len(changes.Updated) > 0 {
+			recordGitContext(root, &git)
+		}
+		link := func(note *Note, file string) {
+			note.File = file
+			note.Anchor = anchor.Capture(contents[file], note.Line)
+			if git.Commit != "" {
+				note.Base = git.Commit
+			}
+		}
+
+		var batch store.Batch
+		for _, it := range changes.Added {
+			note := Note{Message: it.Message(), Tags: []string{it.Tag(), scan.Tag}}
+			note.SetSpan(store.Span{Line: it.Line})
+			note.Commit, note.Branch, note.Author = git.Commit, git.Branch, git.Author
+			link(&note, it.File)
+			batch.Add = append(batch.Add, note)
+		}
+
+		var updated []string
+		for _, m := range changes.Updated {
+			note := m.Note
+			was := note.Location()
+			reopened := note.HasTag(scan.ResolvedTag)
+			note.Tags = slices.DeleteFunc(slices.Clone(note.Tags), func(t string) bool { return t == scan.ResolvedTag })
+			note.SetSpan(store.Span{Line: m.Item.Line})
+			link(&note, m.Item.File)
+
+			what := "moved from " + was
+			if reopened {
+				what = "found again"
+			}
+			batch.Update = append(batch.Update, note)
+			updated = append(updated, what)
+		}
+
+		for _, note := range changes.Resolved {
+			note.Tags = append(slices.Clone(note.Tags), scan.ResolvedTag)
+			batch.Update = append(batch.Update, note)
+		}
+
+		added := batch.Add
+		if !scanDryRun && len(batch.Add)+len(batch.Update) > 0 {
+			if added, err = s.Apply(batch); err != nil {
+				fmt.Println("Error saving notes:", err)
+				return
+			}
+		}
+
+		id := color.New(color.FgHiCyan).SprintFunc()
+		for _, note := range added {
+			shortID := "(new)"
+			if note.ID != "" {
+				shortID = note.ShortID()
+			}
+			fmt.Printf("%s %s %s %s\n", color.GreenString("added   "), id(shortID), note.Location(), firstLine(note.Message))
+		}
+		for i, note := range batch.Update {
+			if i < len(updated) {
+				fmt.Printf("%s %s %s %s (%s)\n", color.YellowString("updated "), id(note.ShortID()), note.Location(), firstLine(note.Message), updated[i])
+			} else {
+				fmt.Printf("%s %s %s %s\n", color.HiBlackString("resolved"), id(note.ShortID()), note.Location(), firstLine(note.Message))
+			}
+		}
+
+		verb := ""
+		if scanDryRun {
+			verb = " (dry run, nothing saved)"
+		}
+		fmt.Printf("Scanned %d file(s): %d added, %d updated, %d resolved, %d unchanged%s\n",
+			len(contents), len(changes.Added), len(changes.Updated), len(changes.Resolved), changes.Unchanged, verb)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+
+	scanCmd.Flags().BoolVarP(&scanDryRun, "dry-run", "n", false, "Show what would change without saving anything")
+	scanCmd.Flags().StringSliceVarP(&scanMarkers, "markers", "m", scan.Markers, "Comma-separated markers to look for")
+}
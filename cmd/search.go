@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/search"
+	"spjoes/notes/store"
+)
+
+var searchLimit int
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <terms...>",
+	Short: "Search notes by relevance",
+	Long: `Searches the messages, tags and file names of notes and lists the
+matches most relevant first, with the matching words highlighted.
+
+Words are matched by their stem, so "caching" also finds "cached" and
+"caches". Notes matching more of the words rank higher.
+
+Enable the search index with "notes index" to keep it under .notes/index
+instead of rebuilding it for every search.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+
+		// taken before loading, so notes written meanwhile are synced next time
+		fingerprint := store.Fingerprint(s.Root())
+		notes, err := s.Load()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		idx, err := searchIndex(s, notes, fingerprint)
+		if err != nil {
+			fmt.Println("Error updating search index:", err)
+			return
+		}
+
+		text := strings.Join(args, " ")
+		hits := idx.Search(text)
+		if len(hits) == 0 {
+			fmt.Println("No notes found")
+			return
+		}
+		if searchLimit > 0 && len(hits) > searchLimit {
+			hits = hits[:searchLimit]
+		}
+
+		byID := make(map[string]Note, len(notes))
+		for _, n := range notes {
+			byID[n.ID] = n
+		}
+		terms := search.QueryTerms(text)
+		bold := color.New(color.FgHiYellow, color.Bold).SprintFunc()
+		mark := func(w string) string { return bold(w) }
+		for _, hit := range hits {
+			n := byID[hit.ID]
+			id := color.New(color.FgHiCyan).Sprint(n.ShortID())
+			first, rest, _ := strings.Cut(n.Message, "\n")
+			message := search.Highlight(first, terms, mark)
+
+			location := ""
+			if n.File != "" {
+				location = " → " + search.Highlight(n.Location(), terms, mark)
+			}
+
+			fmt.Printf("[%s] %s%s\n", id, message, location)
+			if rest != "" {
+				fmt.Println(search.Highlight(indentLines(rest), terms, mark))
+			}
+			if len(n.Tags) > 0 {
+				tags := make([]string, len(n.Tags))
+				for i, tag := range n.Tags {
+					tags[i] = search.Highlight(tag, terms, mark)
+					if tags[i] == tag {
+						tags[i] = color.GreenString("%s", tag)
+					}
+				}
+				fmt.Printf("    Tags: %s\n", strings.Join(tags, ", "))
+			}
+			fmt.Printf("    %s\n\n", color.HiBlackString("score %.2f", hit.Score))
+		}
+	},
+}
+
+// searchIndex returns the search index of the notes, building one in memory
+// when the persisted index is disabled. The persisted index is only synced
+// with the notes when fingerprint, the store.Fingerprint taken before they
+// were loaded, shows they changed since it was last synced.
+func searchIndex(s store.NoteStore, notes []Note, fingerprint uint64) (*search.Index, error) {
+	if _, ok := s.(*search.IndexedStore); !ok {
+		return search.Build(notes), nil
+	}
+	idx := search.Load(s.Root())
+	if idx.Fingerprint == fingerprint {
+		return idx, nil
+	}
+	idx.Sync(notes)
+	idx.Fingerprint = fingerprint
+	if err := idx.Save(s.Root()); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of results (0 for all)")
+}
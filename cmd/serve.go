@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+var serveOrigins []string
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the notes of the project over a local HTTP/JSON API",
+	Long: `Serves the notes of the current project over HTTP, so dashboards, browser
+extensions and scripts can read and write them without running notes:
+
+  GET    /notes        list notes; filter with ?q=<query> (as in notes list),
+                       ?file=, ?tag= (repeatable), ?branch=, ?author=, ?line=
+  POST   /notes        add a note from {"message", "file", "line", "tags", ...}
+  GET    /notes/{id}   get a note by its full or 8-character ID
+  PATCH  /notes/{id}   change the fields given in the body
+  DELETE /notes/{id}   delete a note
+  GET    /events       a server-sent event stream of created, updated and
+                       deleted notes, including changes made outside the server;
+                       missed events are not replayed, so reload /notes after
+                       reconnecting
+
+Notes use the schema of notes list --format json. Responses carry an ETag;
+send it back in If-Match with PATCH or DELETE to fail with 412 when the note
+changed since it was read.
+
+The server listens on 127.0.0.1 only by default. Browsers may only call it
+from the origins given with --allow-origin.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		api, err := newAPIServer(s, serveAddr, serveOrigins)
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		runServer(serveAddr, api, api, "Serving notes of %s on http://%s (Ctrl+C to stop)\n")
+	},
+}
+
+// runServer serves handler on addr until interrupted, then shuts down,
+// ending the event streams of api. The banner is printed with the project
+// root and the address once the server listens.
+func runServer(addr string, handler http.Handler, api *apiServer, banner string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Println("Error starting server:", err)
+		return
+	}
+	if host, _, _ := net.SplitHostPort(addr); host != "localhost" && !net.ParseIP(host).IsLoopback() {
+		fmt.Println("Warning: the server has no authentication and is reachable from other machines")
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	server.RegisterOnShutdown(api.close)
+	go api.watch()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Printf(banner, api.root, listener.Addr())
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("Error serving notes:", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().StringSliceVar(&serveOrigins, "allow-origin", nil, "Origins browsers may call the API from, e.g. http://localhost:3000 or chrome-extension://<id> (repeatable; * for any)")
+}
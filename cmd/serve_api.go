@@ -0,0 +1,627 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/query"
+	"spjoes/notes/store"
+)
+
+// servePollInterval is how often the API server checks the store for
+// changes to stream to /events, including changes made outside the server.
+const servePollInterval = time.Second
+
+// serveHeartbeat is how often an idle event stream gets a comment, so
+// proxies and browsers keep the connection open.
+const serveHeartbeat = 15 * time.Second
+
+// maxNoteBody limits the size of the JSON body of a POST or PATCH.
+const maxNoteBody = 1 << 20
+
+// apiEvent is a change to the store sent to /events subscribers.
+type apiEvent struct {
+	id   int
+	kind string
+	data []byte
+}
+
+// apiServer serves the notes of a project as JSON over HTTP.
+type apiServer struct {
+	store   store.NoteStore
+	root    string
+	mux     *http.ServeMux
+	origins map[string]bool
+	// host is the host of the listen address, which requests may name
+	// besides localhost and loopback addresses.
+	host string
+
+	// writeMu serializes writes, so the If-Match of a PATCH or DELETE is
+	// checked against the note it then changes.
+	writeMu sync.Mutex
+
+	// mu guards the state of the change stream.
+	mu          sync.Mutex
+	fingerprint uint64
+	etags       map[string]string
+	lastEvent   int
+	subscribers map[chan apiEvent]bool
+	done        chan struct{}
+}
+
+// newAPIServer returns the API server for a store listening on addr.
+// Browsers may call it from the given origins; other cross-origin requests
+// are refused.
+func newAPIServer(st store.NoteStore, addr string, origins []string) (*apiServer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	a := &apiServer{
+		store:       st,
+		root:        st.Root(),
+		mux:         http.NewServeMux(),
+		origins:     map[string]bool{},
+		host:        strings.ToLower(strings.Trim(host, "[]")),
+		subscribers: map[chan apiEvent]bool{},
+		done:        make(chan struct{}),
+	}
+	for _, o := range origins {
+		a.origins[strings.TrimSuffix(o, "/")] = true
+	}
+
+	// the first snapshot of the store, which events are relative to
+	a.fingerprint = storeFingerprint(a.root)
+	notes, err := st.Load()
+	if err != nil {
+		return nil, err
+	}
+	a.etags = noteETags(notes)
+
+	a.mux.HandleFunc("GET /notes", a.listNotes)
+	a.mux.HandleFunc("POST /notes", a.createNote)
+	a.mux.HandleFunc("GET /notes/{id}", a.getNote)
+	a.mux.HandleFunc("PATCH /notes/{id}", a.updateNote)
+	a.mux.HandleFunc("DELETE /notes/{id}", a.deleteNote)
+	a.mux.HandleFunc("GET /events", a.events)
+	return a, nil
+}
+
+func (a *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.allowHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed; use localhost or the address given with --addr", r.Host))
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if !a.origins[origin] && !a.origins["*"] {
+			writeError(w, http.StatusForbidden, fmt.Errorf("origin %s is not allowed; start the server with --allow-origin %s", origin, origin))
+			return
+		}
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", "ETag, Location")
+		h.Add("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+			h.Set("Access-Control-Allow-Headers", "Content-Type, If-Match, If-None-Match")
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	a.mux.ServeHTTP(w, r)
+}
+
+// allowHost reports whether a request may name host in its Host header:
+// localhost, a loopback address or the host of the listen address. Other
+// names are refused, so a web page cannot reach the server through a name
+// of its own that resolves to this machine (DNS rebinding). When listening
+// on every interface, any IP address is accepted too.
+func (a *apiServer) allowHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	if host == "" {
+		return false
+	}
+	if host == "localhost" || host == a.host {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	listen := net.ParseIP(a.host)
+	return a.host == "" || (listen != nil && listen.IsUnspecified())
+}
+
+// close ends the event streams, so the server can shut down.
+func (a *apiServer) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-a.done:
+	default:
+		close(a.done)
+	}
+}
+
+// noteInput is the body of a POST or PATCH. Fields left out keep their
+// value; setting any of the line fields replaces the note's whole span.
+// Read-only fields of the note schema, like id or status, are ignored, so
+// a note can be sent back as it was read.
+type noteInput struct {
+	Message   *string   `json:"message"`
+	File      *string   `json:"file"`
+	Line      *int      `json:"line"`
+	Column    *int      `json:"column"`
+	EndLine   *int      `json:"end_line"`
+	EndColumn *int      `json:"end_column"`
+	Tags      *[]string `json:"tags"`
+}
+
+// apply changes note by the fields of the input, relinking it when its
+// file or lines change.
+func (in noteInput) apply(root string, note *Note) error {
+	if in.Message != nil {
+		if strings.TrimSpace(*in.Message) == "" {
+			return errors.New("message must not be empty")
+		}
+		note.Message = *in.Message
+	}
+	if in.Tags != nil {
+		note.Tags = *in.Tags
+		if len(note.Tags) == 0 {
+			note.Tags = nil
+		}
+	}
+
+	lines := in.Line != nil || in.Column != nil || in.EndLine != nil || in.EndColumn != nil
+	if in.File == nil && !lines {
+		return nil
+	}
+	file := note.File
+	if in.File != nil {
+		file = store.NormalizePath(root, *in.File)
+		if file == "." {
+			file = ""
+		}
+	}
+	span := note.Span()
+	if lines {
+		var err error
+		if span, err = inputSpan(in); err != nil {
+			return err
+		}
+	}
+	if file == "" {
+		if span.Line > 0 {
+			return errors.New("a note needs a file to be linked to a line")
+		}
+		span = store.Span{}
+	}
+
+	if file != note.File || span != note.Span() {
+		note.SetSpan(span)
+		relink(root, note, file)
+	}
+	return nil
+}
+
+// inputSpan returns the span given by the line fields of the input.
+func inputSpan(in noteInput) (store.Span, error) {
+	value := func(n *int) int {
+		if n == nil {
+			return 0
+		}
+		return *n
+	}
+	sp := store.Span{Line: value(in.Line), Column: value(in.Column), EndLine: value(in.EndLine), EndColumn: value(in.EndColumn)}
+	if sp.Line < 0 || sp.Column < 0 || sp.EndLine < 0 || sp.EndColumn < 0 {
+		return store.Span{}, errors.New("line and column numbers start at 1")
+	}
+	if sp.Line == 0 {
+		if sp != (store.Span{}) {
+			return store.Span{}, errors.New("a column or end needs a line")
+		}
+		return sp, nil
+	}
+	if sp.EndLine == 0 && sp.EndColumn > 0 {
+		sp.EndLine = sp.Line
+	}
+	// ParseSpan validates the span and normalizes single-line ranges
+	return store.ParseSpan(sp.String())
+}
+
+func (a *apiServer) listNotes(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q, err := query.Parse(params.Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	line := 0
+	if s := params.Get("line"); s != "" {
+		if line, err = strconv.Atoi(s); err != nil || line < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid line %q", s))
+			return
+		}
+	}
+	file := store.NormalizePath(a.root, params.Get("file"))
+
+	notes, err := a.store.Load()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	locator := anchor.NewLocator(a.root)
+	records := []noteRecord{}
+	for _, stored := range notes {
+		n, status := locate(locator, stored)
+		switch {
+		case file != "" && n.File != file:
+			continue
+		case slices.ContainsFunc(params["tag"], func(tag string) bool { return !n.HasTag(tag) }):
+			continue
+		case params.Has("branch") && n.Branch != params.Get("branch"):
+			continue
+		case params.Has("author") && !strings.Contains(strings.ToLower(n.Author), strings.ToLower(params.Get("author"))):
+			continue
+		case line > 0 && !n.Covers(line):
+			continue
+		case !q.Match(n):
+			continue
+		}
+		records = append(records, newNoteRecord(locatedNote{Note: n, Status: status, StoredLine: stored.Line}))
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the list has no version of its own, so its tag is that of its body
+	writeBody(w, r, http.StatusOK, etagOf(body), body)
+}
+
+func (a *apiServer) getNote(w http.ResponseWriter, r *http.Request) {
+	note, err := a.store.Get(r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	a.writeNote(w, r, http.StatusOK, note)
+}
+
+func (a *apiServer) createNote(w http.ResponseWriter, r *http.Request) {
+	var in noteInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if in.Message == nil {
+		writeError(w, http.StatusBadRequest, errors.New("message is required"))
+		return
+	}
+
+	var note Note
+	recordGitContext(a.root, &note)
+	if err := in.apply(a.root, &note); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	note, err := a.store.Add(note)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	a.checkChanges()
+
+	w.Header().Set("Location", "/notes/"+note.ID)
+	a.writeNote(w, r, http.StatusCreated, note)
+}
+
+func (a *apiServer) updateNote(w http.ResponseWriter, r *http.Request) {
+	var in noteInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	note, err := a.store.Get(r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ifMatch(r, noteETag(note)) {
+		writeError(w, http.StatusPreconditionFailed, errors.New("note was changed since it was read"))
+		return
+	}
+	if err := in.apply(a.root, &note); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := a.store.Update(note); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	a.checkChanges()
+	a.writeNote(w, r, http.StatusOK, note)
+}
+
+func (a *apiServer) deleteNote(w http.ResponseWriter, r *http.Request) {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	note, err := a.store.Get(r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ifMatch(r, noteETag(note)) {
+		writeError(w, http.StatusPreconditionFailed, errors.New("note was changed since it was read"))
+		return
+	}
+	if err := a.store.Delete(note.ID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	a.checkChanges()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeNote writes a note relocated against its file, tagged with the
+// version of the stored note.
+func (a *apiServer) writeNote(w http.ResponseWriter, r *http.Request, code int, stored Note) {
+	n, status := locate(anchor.NewLocator(a.root), stored)
+	body, err := json.Marshal(newNoteRecord(locatedNote{Note: n, Status: status, StoredLine: stored.Line}))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeBody(w, r, code, noteETag(stored), body)
+}
+
+// events streams changes to the store as server-sent events: "created" and
+// "updated" with the note, and "deleted" with its id and short_id. Events
+// missed while a client was disconnected are not replayed, whatever its
+// Last-Event-ID; clients reload the notes when they reconnect.
+func (a *apiServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	ch := a.subscribe()
+	defer a.unsubscribe(ch)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": notes change stream\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(serveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.done:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e, ok := <-ch:
+			if !ok {
+				// the client fell behind; it reconnects and reloads
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, e.kind, e.data)
+		}
+		flusher.Flush()
+	}
+}
+
+func (a *apiServer) subscribe() chan apiEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch := make(chan apiEvent, 64)
+	a.subscribers[ch] = true
+	return ch
+}
+
+func (a *apiServer) unsubscribe(ch chan apiEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.subscribers[ch] {
+		delete(a.subscribers, ch)
+		close(ch)
+	}
+}
+
+// watch checks the store for changes until the server closes.
+func (a *apiServer) watch() {
+	ticker := time.NewTicker(servePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			a.checkChanges()
+		}
+	}
+}
+
+// checkChanges sends an event for every note created, updated or deleted
+// since the last check.
+func (a *apiServer) checkChanges() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fingerprint := storeFingerprint(a.root)
+	if fingerprint == a.fingerprint {
+		return
+	}
+	notes, err := a.store.Load()
+	if err != nil {
+		// e.g. a half-resolved merge; the next check tries again
+		return
+	}
+	a.fingerprint = fingerprint
+
+	etags := noteETags(notes)
+	locator := anchor.NewLocator(a.root)
+	for _, stored := range notes {
+		kind := "updated"
+		if old, ok := a.etags[stored.ID]; !ok {
+			kind = "created"
+		} else if old == etags[stored.ID] {
+			continue
+		}
+		n, status := locate(locator, stored)
+		data, _ := json.Marshal(newNoteRecord(locatedNote{Note: n, Status: status, StoredLine: stored.Line}))
+		a.broadcast(kind, data)
+	}
+	var deleted []string
+	for id := range a.etags {
+		if _, ok := etags[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	slices.Sort(deleted)
+	for _, id := range deleted {
+		data, _ := json.Marshal(map[string]string{"id": id, "short_id": Note{ID: id}.ShortID()})
+		a.broadcast("deleted", data)
+	}
+	a.etags = etags
+}
+
+// broadcast sends an event to every subscriber. Subscribers too slow to
+// take it are dropped. The caller holds a.mu.
+func (a *apiServer) broadcast(kind string, data []byte) {
+	a.lastEvent++
+	e := apiEvent{id: a.lastEvent, kind: kind, data: data}
+	for ch := range a.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(a.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// noteETag returns the entity tag of a stored note, which changes whenever
+// the note does.
+func noteETag(n Note) string {
+	data, _ := json.Marshal(n)
+	return etagOf(data)
+}
+
+func noteETags(notes []Note) map[string]string {
+	etags := make(map[string]string, len(notes))
+	for _, n := range notes {
+		etags[n.ID] = noteETag(n)
+	}
+	return etags
+}
+
+func etagOf(data []byte) string {
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// ifMatch reports whether the request's If-Match header, if any, matches
+// etag.
+func ifMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBody writes a JSON body with its entity tag, or 304 Not Modified
+// when the client already has it.
+func writeBody(w http.ResponseWriter, r *http.Request, code int, etag string, body []byte) {
+	h := w.Header()
+	h.Set("ETag", etag)
+	if code == http.StatusOK && r.Method == http.MethodGet {
+		for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+			if tag = strings.TrimSpace(tag); tag == etag || tag == "*" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+// writeError writes an error as {"error": "..."}.
+func writeError(w http.ResponseWriter, code int, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+// writeStoreError writes an error returned by the store with a matching
+// status.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrAmbiguousID):
+		writeError(w, http.StatusConflict, errors.New("note id is ambiguous; use the full id"))
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// readJSON decodes the JSON body of a request. Requiring the JSON content
+// type keeps other sites from posting forms to the server.
+func readJSON(r *http.Request, v any) error {
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		return errors.New("the body must be application/json")
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNoteBody+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxNoteBody {
+		return errors.New("the body is too large")
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/highlight"
+	"spjoes/notes/store"
+)
+
+var showContext int
+var showRaw bool
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a note in full with the code it refers to",
+	Long: `Shows a single note in full, with all of its metadata. When the note is
+linked to a line of a file, an excerpt of the file around that line is
+printed with syntax highlighting.
+
+The message is rendered as markdown on a terminal. Use --raw, or pipe the
+output, to print it as written.
+
+You must supply the note ID (first 8 chars or full).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if showContext < 0 {
+			fmt.Println("Error: --context must not be negative")
+			return
+		}
+
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+
+		stored, err := s.Get(args[0])
+		if errors.Is(err, store.ErrNotFound) {
+			fmt.Printf("No note found with ID %s\n", args[0])
+			return
+		}
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		n, status := locate(anchor.NewLocator(s.Root()), stored)
+
+		label := color.New(color.FgHiBlack).SprintFunc()
+		fmt.Printf("%s %s\n\n", label("Note"), color.HiCyanString(n.ID))
+		fmt.Println(renderMessage(n.Message, showRaw, 0))
+		fmt.Println()
+
+		if n.File != "" {
+			location := n.Location()
+			switch status {
+			case anchor.StatusMoved:
+				location += color.YellowString(" (moved from line %d)", stored.Line)
+			case anchor.StatusOrphaned:
+				location += color.RedString(" (orphaned)")
+			}
+			fmt.Printf("%s %s\n", label("File:   "), location)
+		}
+		if len(n.Tags) > 0 {
+			fmt.Printf("%s %s\n", label("Tags:   "), color.GreenString(strings.Join(n.Tags, ", ")))
+		}
+		fmt.Printf("%s %s\n", label("Created:"), n.CreatedAt.Format(time.RFC1123))
+		if n.Author != "" {
+			fmt.Printf("%s %s\n", label("Author: "), n.Author)
+		}
+		if n.Branch != "" {
+			fmt.Printf("%s %s\n", label("Branch: "), n.Branch)
+		}
+		if n.Commit != "" {
+			fmt.Printf("%s %s\n", label("Commit: "), n.Commit)
+		}
+
+		if n.File == "" || n.Line <= 0 {
+			return
+		}
+
+		lines, err := anchor.ReadLines(filepath.Join(s.Root(), filepath.FromSlash(n.File)))
+		if err != nil {
+			fmt.Printf("\n%s\n", color.RedString("Cannot read %s: %v", n.File, err))
+			return
+		}
+		fmt.Println()
+		printExcerpt(n.File, lines, n, showContext)
+	},
+}
+
+// printExcerpt prints the lines of a file covered by the note, plus context
+// lines on either side, with line numbers and syntax highlighting.
+func printExcerpt(file string, lines []string, n Note, context int) {
+	if n.Line > len(lines) {
+		fmt.Println(color.RedString("Line %d is past the end of %s (%d lines)", n.Line, file, len(lines)))
+		return
+	}
+
+	first := max(1, n.Line-context)
+	last := min(len(lines), n.LastLine()+context)
+	tokens := highlight.Lines(highlight.ForFile(file), lines[:last])
+
+	width := len(fmt.Sprint(last))
+	gutter := color.New(color.FgHiBlack).SprintFunc()
+	marker := color.New(color.FgHiYellow, color.Bold).SprintFunc()
+	for i := first; i <= last; i++ {
+		num := fmt.Sprintf("%*d", width, i)
+		if n.Covers(i) {
+			fmt.Printf("%s %s %s %s\n", marker("▶"), marker(num), gutter("│"), colorTokens(tokens[i-1]))
+		} else {
+			fmt.Printf("  %s %s %s\n", gutter(num), gutter("│"), colorTokens(tokens[i-1]))
+		}
+	}
+}
+
+// colorTokens renders highlighted tokens with terminal colours.
+func colorTokens(toks []highlight.Token) string {
+	var b strings.Builder
+	for _, t := range toks {
+		text := strings.ReplaceAll(t.Text, "\t", "    ")
+		switch t.Kind {
+		case highlight.Keyword:
+			b.WriteString(color.MagentaString("%s", text))
+		case highlight.String:
+			b.WriteString(color.GreenString("%s", text))
+		case highlight.Comment:
+			b.WriteString(color.HiBlackString("%s", text))
+		case highlight.Number:
+			b.WriteString(color.YellowString("%s", text))
+		default:
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().BoolVar(&showRaw, "raw", false, "Print the message as written instead of rendering its markdown")
+	showCmd.Flags().IntVarP(&showContext, "context", "C", 3, "Number of lines of the file to show around the note")
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// fuzzySep separates the fields of a note in its fuzzy search target.
+const fuzzySep = "  "
+
+// noteDelegate renders notes like the default delegate, highlighting the
+// characters matched by a fuzzy search in the title and description.
+type noteDelegate struct {
+	list.DefaultDelegate
+}
+
+func newNoteDelegate() noteDelegate {
+	d := noteDelegate{list.NewDefaultDelegate()}
+	d.Styles.FilterMatch = lipgloss.NewStyle().Underline(true).Foreground(lipgloss.Color("#FFD75F"))
+	return d
+}
+
+func (d noteDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	ni, ok := item.(NoteItem)
+	if !ok || len(ni.matched) == 0 || m.Width() <= 0 {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	s := &d.Styles
+	titleStyle, descStyle := s.NormalTitle, s.NormalDesc
+	if index == m.Index() {
+		titleStyle, descStyle = s.SelectedTitle, s.SelectedDesc
+	}
+
+	width := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+	titleRunes, descRunes := ni.highlights()
+	title, titleRunes := truncateRunes(ni.Title(), width, titleRunes)
+	desc, descRunes := truncateRunes(ni.Description(), width, descRunes)
+
+	title = lipgloss.StyleRunes(title, titleRunes, s.FilterMatch.Inherit(titleStyle.Inline(true)), titleStyle.Inline(true))
+	desc = lipgloss.StyleRunes(desc, descRunes, s.FilterMatch.Inherit(descStyle.Inline(true)), descStyle.Inline(true))
+	fmt.Fprintf(w, "%s\n%s", titleStyle.Render(title), descStyle.Render(desc))
+}
+
+// truncateRunes shortens s to width runes with an ellipsis, dropping the
+// highlighted rune indices that no longer fit.
+func truncateRunes(s string, width int, highlighted []int) (string, []int) {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s, highlighted
+	}
+	runes := []rune(s)
+	s = string(runes[:width-1]) + "…"
+	kept := highlighted[:0:0]
+	for _, r := range highlighted {
+		if r < width-1 {
+			kept = append(kept, r)
+		}
+	}
+	return s, kept
+}
+
+// fuzzyTarget is the text a fuzzy search matches a note against: its
+// message, file and tags.
+func (i NoteItem) fuzzyTarget() string {
+	return i.Message + fuzzySep + i.File + fuzzySep + strings.Join(i.Tags, ", ")
+}
+
+// highlights maps the byte offsets matched in the fuzzy target to rune
+// indices of the title and description.
+func (i NoteItem) highlights() (title, desc []int) {
+	titleText, descText := i.Title(), i.Description()
+	// the title is the first line of the message, possibly cut short
+	titleLen := len(titleText)
+	if titleText != firstLine(i.Message) {
+		titleLen -= len("...")
+	}
+	fileStart := len(i.Message) + len(fuzzySep)
+	tagsStart := fileStart + len(i.File) + len(fuzzySep)
+	tagsAt := -1
+	if len(i.Tags) > 0 {
+		tagsAt = strings.Index(descText, " ["+strings.Join(i.Tags, ", ")+"]") + len(" [")
+	}
+
+	for _, p := range i.matched {
+		switch {
+		case p < titleLen:
+			title = append(title, utf8.RuneCountInString(titleText[:p]))
+		case p >= fileStart && p < fileStart+len(i.File):
+			// the description starts with " " and the file's location
+			desc = append(desc, utf8.RuneCountInString(descText[:1+p-fileStart]))
+		case p >= tagsStart && tagsAt >= len(" ["):
+			desc = append(desc, utf8.RuneCountInString(descText[:tagsAt+p-tagsStart]))
+		}
+	}
+	return title, desc
+}
+
+// fuzzyTargets adapts notes to a fuzzy.Source.
+type fuzzyTargets []NoteItem
+
+func (t fuzzyTargets) String(i int) string { return t[i].fuzzyTarget() }
+func (t fuzzyTargets) Len() int            { return len(t) }
+
+// fuzzyFilterItems returns the notes fuzzily matching pattern, best matches
+// first, with the matched characters recorded for highlighting.
+func fuzzyFilterItems(all []NoteItem, pattern string) []list.Item {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		items := make([]list.Item, len(all))
+		for i, ni := range all {
+			items[i] = ni
+		}
+		return items
+	}
+
+	matches := fuzzy.FindFrom(pattern, fuzzyTargets(all))
+	items := make([]list.Item, len(matches))
+	for i, match := range matches {
+		ni := all[match.Index]
+		ni.matched = append([]int(nil), match.MatchedIndexes...)
+		items[i] = ni
+	}
+	return items
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/highlight"
+	"spjoes/notes/markdown"
+)
+
+var (
+	detailLabel = lipgloss.NewStyle().Foreground(lipgloss.Color("#767676"))
+	detailID    = lipgloss.NewStyle().Foreground(lipgloss.Color("#5DAFF4"))
+)
+
+// lipStyle returns a function rendering text with a lipgloss style.
+func lipStyle(s lipgloss.Style) func(string) string {
+	return func(text string) string { return s.Render(text) }
+}
+
+// tokenStyles colours highlighted code in the TUI like colorTokens does on
+// the command line.
+var tokenStyles = map[highlight.Kind]lipgloss.Style{
+	highlight.Keyword: lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
+	highlight.String:  lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+	highlight.Comment: lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+	highlight.Number:  lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+}
+
+// tuiTheme renders markdown in the TUI.
+var tuiTheme = markdown.Theme{
+	Heading: func(level int, text string) string {
+		s := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#5DAFF4"))
+		if level == 1 {
+			s = s.Underline(true)
+		}
+		return s.Render(text)
+	},
+	Bold:   lipStyle(lipgloss.NewStyle().Bold(true)),
+	Italic: lipStyle(lipgloss.NewStyle().Italic(true)),
+	Code:   lipStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD75F"))),
+	Link:   lipStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#5FAFFF")).Underline(true)),
+	URL:    lipStyle(detailLabel),
+	Quote:  lipStyle(detailLabel),
+	Marker: lipStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#5DAFF4"))),
+	Rule:   lipStyle(detailLabel),
+	Token: func(kind highlight.Kind, text string) string {
+		if s, ok := tokenStyles[kind]; ok {
+			return s.Render(text)
+		}
+		return text
+	},
+}
+
+// renderDetail renders a note for the detail view: its ID, the message as
+// markdown, and what the note is attached to.
+func renderDetail(item NoteItem, width int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n\n", detailLabel.Render("Note"), detailID.Render(item.ID))
+	b.WriteString(markdown.Render(item.Message, min(width, maxRenderWidth), tuiTheme))
+	b.WriteString("\n\n")
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s %s\n", detailLabel.Render(fmt.Sprintf("%-8s", name)), value)
+		}
+	}
+	if item.File != "" {
+		loc := item.Location()
+		if item.Status != anchor.StatusUnchanged {
+			loc += " (" + string(item.Status) + ")"
+		}
+		field("File", loc)
+	}
+	field("Tags", strings.Join(item.Tags, ", "))
+	if item.Author != "" {
+		field("Author", gitinfo.AuthorName(item.Author))
+	}
+	field("Branch", item.Branch)
+	if item.Commit != "" {
+		field("Commit", item.Commit[:min(len(item.Commit), 12)])
+	}
+	if !item.CreatedAt.IsZero() {
+		field("Created", item.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
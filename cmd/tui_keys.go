@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+
+	"spjoes/notes/config"
+)
+
+// keyMap holds the key bindings of the TUI's note list.
+type keyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+
+	View   key.Binding
+	Add    key.Binding
+	Edit   key.Binding
+	Delete key.Binding
+	Open   key.Binding
+	Search key.Binding
+
+	Back key.Binding
+	Help key.Binding
+	Quit key.Binding
+
+	// Submit, Cancel and ToggleFuzzy work in the search bar and the add and
+	// edit forms; ConfirmDelete answers the delete prompt.
+	Submit        key.Binding
+	Cancel        key.Binding
+	ToggleFuzzy   key.Binding
+	ConfirmDelete key.Binding
+}
+
+// actions lists the actions that can be bound, by the name used in the
+// user config, with the help shown for them.
+var actions = []struct {
+	name    string
+	help    string
+	binding func(*keyMap) *key.Binding
+}{
+	{"up", "up", func(k *keyMap) *key.Binding { return &k.Up }},
+	{"down", "down", func(k *keyMap) *key.Binding { return &k.Down }},
+	{"page-up", "previous page", func(k *keyMap) *key.Binding { return &k.PageUp }},
+	{"page-down", "next page", func(k *keyMap) *key.Binding { return &k.PageDown }},
+	{"top", "go to start", func(k *keyMap) *key.Binding { return &k.Top }},
+	{"bottom", "go to end", func(k *keyMap) *key.Binding { return &k.Bottom }},
+	{"view", "view note", func(k *keyMap) *key.Binding { return &k.View }},
+	{"add", "add", func(k *keyMap) *key.Binding { return &k.Add }},
+	{"edit", "edit", func(k *keyMap) *key.Binding { return &k.Edit }},
+	{"delete", "delete", func(k *keyMap) *key.Binding { return &k.Delete }},
+	{"open", "open in editor", func(k *keyMap) *key.Binding { return &k.Open }},
+	{"search", "search", func(k *keyMap) *key.Binding { return &k.Search }},
+	{"back", "back", func(k *keyMap) *key.Binding { return &k.Back }},
+	{"help", "toggle help", func(k *keyMap) *key.Binding { return &k.Help }},
+	{"quit", "quit", func(k *keyMap) *key.Binding { return &k.Quit }},
+	{"submit", "continue", func(k *keyMap) *key.Binding { return &k.Submit }},
+	{"cancel", "cancel", func(k *keyMap) *key.Binding { return &k.Cancel }},
+	{"toggle-fuzzy", "exact/fuzzy search", func(k *keyMap) *key.Binding { return &k.ToggleFuzzy }},
+	{"confirm-delete", "confirm delete", func(k *keyMap) *key.Binding { return &k.ConfirmDelete }},
+}
+
+// listActions are the actions the note list answers to. A key may only be
+// bound to one of them; back only acts in the detail view, and the other
+// actions in prompts that take over the keyboard, so they may share keys.
+var listActions = []string{
+	"up", "down", "page-up", "page-down", "top", "bottom",
+	"view", "add", "edit", "delete", "open", "search", "help", "quit",
+}
+
+// keyPresets are the key bindings selected by the keymap setting. The
+// default preset keeps the keys the TUI has always had.
+var keyPresets = map[string]map[string][]string{
+	"default": {
+		"up":        {"up", "k"},
+		"down":      {"down", "j"},
+		"page-up":   {"left", "h", "pgup", "b", "u"},
+		"page-down": {"right", "l", "pgdown", "f", "d"},
+		"top":       {"home", "g"},
+		"bottom":    {"end", "G"},
+		"view":      {"enter"},
+		"add":       {"ctrl+a"},
+		"edit":      {"ctrl+e"},
+		"delete":    {"delete", "ctrl+d"},
+		"open":      {"o"},
+		"search":    {"/", "ctrl+f"},
+		"back":      {"esc", "backspace"},
+		"help":      {"?"},
+		"quit":      {"q", "esc", "ctrl+c", "ctrl+q"},
+
+		"submit":         {"enter"},
+		"cancel":         {"esc", "ctrl+c"},
+		"toggle-fuzzy":   {"tab"},
+		"confirm-delete": {"y", "Y", "enter"},
+	},
+	"vim": {
+		"up":        {"k", "up"},
+		"down":      {"j", "down"},
+		"page-up":   {"ctrl+b", "ctrl+u", "pgup"},
+		"page-down": {"ctrl+f", "ctrl+d", "pgdown"},
+		"top":       {"g", "home"},
+		"bottom":    {"G", "end"},
+		"view":      {"enter", "l"},
+		"add":       {"a", "i"},
+		"edit":      {"e", "c"},
+		"delete":    {"x", "d", "delete"},
+		"open":      {"o"},
+		"search":    {"/"},
+		"back":      {"esc", "h"},
+		"help":      {"?"},
+		"quit":      {"q", "ctrl+c"},
+
+		"submit":         {"enter"},
+		"cancel":         {"esc", "ctrl+c"},
+		"toggle-fuzzy":   {"tab"},
+		"confirm-delete": {"y", "Y", "enter"},
+	},
+	"emacs": {
+		"up":        {"ctrl+p", "up"},
+		"down":      {"ctrl+n", "down"},
+		"page-up":   {"alt+v", "pgup"},
+		"page-down": {"ctrl+v", "pgdown"},
+		"top":       {"alt+<", "home"},
+		"bottom":    {"alt+>", "end"},
+		"view":      {"enter"},
+		"add":       {"ctrl+o"},
+		"edit":      {"ctrl+e"},
+		"delete":    {"ctrl+k", "ctrl+d", "delete"},
+		"open":      {"alt+o"},
+		"search":    {"ctrl+s", "ctrl+r"},
+		"back":      {"ctrl+g", "esc"},
+		"help":      {"?", "f1"},
+		"quit":      {"ctrl+q", "ctrl+c"},
+
+		"submit":         {"enter"},
+		"cancel":         {"ctrl+g", "esc", "ctrl+c"},
+		"toggle-fuzzy":   {"tab"},
+		"confirm-delete": {"y", "Y", "enter"},
+	},
+}
+
+// loadKeyMap returns the key bindings chosen in the user config: a preset
+// with any keys the config overrides.
+func loadKeyMap(cfg config.Config) (keyMap, error) {
+	var km keyMap
+	name := cfg.Keymap
+	if name == "" {
+		name = "default"
+	}
+	preset, ok := keyPresets[name]
+	if !ok {
+		return km, fmt.Errorf("unknown keymap %q (expected default, vim or emacs)", cfg.Keymap)
+	}
+
+	known := make(map[string]bool, len(actions))
+	bound := make(map[string][]string, len(actions))
+	for _, a := range actions {
+		known[a.name] = true
+		keys := preset[a.name]
+		if override, ok := cfg.Keys[a.name]; ok {
+			keys = override
+		}
+		bound[a.name] = keys
+		b := key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKeys(keys), a.help))
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+		}
+		*a.binding(&km) = b
+	}
+
+	var unknown []string
+	for name := range cfg.Keys {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return km, fmt.Errorf("unknown action %s in keys (expected %s)", strings.Join(unknown, ", "), actionNames())
+	}
+
+	owner := map[string]string{}
+	for _, name := range listActions {
+		for _, k := range bound[name] {
+			if other, ok := owner[k]; ok && other != name {
+				return km, fmt.Errorf("key %q is bound to both %s and %s", k, other, name)
+			}
+			owner[k] = name
+		}
+	}
+	return km, nil
+}
+
+// actionNames lists the names of the actions that can be bound.
+func actionNames() string {
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = a.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// helpKeys shows the first two keys of a binding in the help.
+func helpKeys(keys []string) string {
+	shown := keys[:min(len(keys), 2)]
+	names := make([]string, len(shown))
+	for i, k := range shown {
+		switch k {
+		case "up":
+			k = "↑"
+		case "down":
+			k = "↓"
+		case "left":
+			k = "←"
+		case "right":
+			k = "→"
+		}
+		names[i] = k
+	}
+	return strings.Join(names, "/")
+}
+
+// ShortHelp returns the bindings shown below the list.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.View, k.Add, k.Edit, k.Delete, k.Search, k.Open, k.Help, k.Quit}
+}
+
+// FullHelp returns the bindings shown by the help overlay, in columns.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.View, k.Open, k.Search, k.Back},
+		{k.Add, k.Edit, k.Delete},
+		{k.Help, k.Quit},
+	}
+}
+
+// applyList makes a list move with the keymap's navigation keys. The list's
+// own quit and help keys are turned off; the model handles those.
+func (k keyMap) applyList(l *list.Model) {
+	l.KeyMap.CursorUp = k.Up
+	l.KeyMap.CursorDown = k.Down
+	l.KeyMap.PrevPage = k.PageUp
+	l.KeyMap.NextPage = k.PageDown
+	l.KeyMap.GoToStart = k.Top
+	l.KeyMap.GoToEnd = k.Bottom
+	l.KeyMap.ShowFullHelp = key.NewBinding()
+	l.KeyMap.CloseFullHelp = key.NewBinding()
+	l.DisableQuitKeybindings()
+}
+
+// applyViewport makes a viewport scroll with the keymap's navigation keys.
+func (k keyMap) applyViewport(v *viewport.Model) {
+	v.KeyMap.Up = k.Up
+	v.KeyMap.Down = k.Down
+	v.KeyMap.PageUp = k.PageUp
+	v.KeyMap.PageDown = k.PageDown
+}
@@ -1,17 +1,25 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/config"
+	"spjoes/notes/files"
+	"spjoes/notes/gitinfo"
+	"spjoes/notes/query"
+	"spjoes/notes/store"
 )
 
 type model struct {
@@ -22,6 +30,7 @@ type model struct {
 	editStage        int
 	editItem         NoteItem
 	textInput        textinput.Model
+	messageInput     textarea.Model
 	fileList         list.Model
 	newMsg           string
 	selectedFile     string
@@ -30,40 +39,57 @@ type model struct {
 	height           int
 	searchMode       bool
 	searchInput      textinput.Model
+	searchErr        string
+	fuzzySearch      bool
+	showDetail       bool
+	detail           viewport.Model
+	previews         map[string]previewFile
+	keys             keyMap
+	help             help.Model
+	showHelp         bool
 	allItems         []NoteItem
+	store            store.NoteStore
 }
 
+// editorClosedMsg is sent when the editor started with "o" exits.
+type editorClosedMsg struct{ err error }
+
 type NoteItem struct {
-	ID        string
-	Message   string
-	File      string
-	Line      int
-	CreatedAt time.Time
-	Tags      []string
+	Note
+	// Status tells whether Line was relocated from the stored line.
+	Status anchor.Status
+	// matched holds the byte offsets of fuzzyTarget matched by a fuzzy
+	// search.
+	matched []int
 }
 
 var _ list.Item = (*NoteItem)(nil)
 
 func (i NoteItem) Title() string {
-	if len(i.Message) > 40 {
-		return i.Message[:37] + "..."
+	title := firstLine(i.Message)
+	if len(title) > 40 {
+		return title[:37] + "..."
 	}
-	return i.Message
+	return title
 }
 
 func (i NoteItem) Description() string {
 	loc := ""
 	if i.File != "" {
-		loc += " "
-		if i.Line > 0 {
-			loc += fmt.Sprintf("%s:%d", i.File, i.Line)
-		} else {
-			loc += i.File
-		}
+		loc += " " + i.Location()
+	}
+	if i.Status != anchor.StatusUnchanged {
+		loc += " (" + string(i.Status) + ")"
 	}
 	if len(i.Tags) > 0 {
 		loc += " [" + strings.Join(i.Tags, ", ") + "]"
 	}
+	if i.Author != "" {
+		loc += " by " + gitinfo.AuthorName(i.Author)
+	}
+	if i.Branch != "" {
+		loc += " on " + i.Branch
+	}
 	return loc
 }
 
@@ -72,22 +98,31 @@ func (i NoteItem) FilterValue() string {
 }
 
 func initialModel() (model, error) {
-	notes, err := LoadAllNotes()
+	s, err := openStore()
+	if err != nil {
+		return model{}, err
+	}
+
+	notes, err := s.Load()
+	if err != nil {
+		return model{}, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return model{}, err
+	}
+	keys, err := loadKeyMap(cfg)
 	if err != nil {
 		return model{}, err
 	}
 
 	items := make([]list.Item, len(notes))
 	all := make([]NoteItem, len(notes))
+	locator := anchor.NewLocator(s.Root())
 	for i, n := range notes {
-		ni := NoteItem{
-			ID:        n.ID,
-			Message:   n.Message,
-			File:      n.File,
-			Line:      n.Line,
-			CreatedAt: n.CreatedAt,
-			Tags:      n.Tags,
-		}
+		ni := NoteItem{}
+		ni.Note, ni.Status = locate(locator, n)
 		items[i] = ni
 		all[i] = ni
 	}
@@ -95,19 +130,26 @@ func initialModel() (model, error) {
 	const listWidth = 20
 	const listHeight = 10
 
-	delegate := list.NewDefaultDelegate()
-
-	l := list.New(items, delegate, listWidth, listHeight)
-	l.Title = "Notes (press ↑/↓ to scroll, Delete to delete, q to quit)"
+	l := list.New(items, newNoteDelegate(), listWidth, listHeight)
+	l.Title = "Notes"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.SetShowHelp(false)
+	keys.applyList(&l)
 
 	ti := textinput.New()
 	ti.Placeholder = ""
-	ti.CharLimit = 256
+	ti.CharLimit = 0
 	ti.Width = listWidth - 2
 
+	// messages may span several lines; Enter moves on to the next step
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.ShowLineNumbers = false
+	ta.SetWidth(listWidth - 2)
+	ta.SetHeight(6)
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+
 	si := textinput.New()
 	si.Placeholder = "Search (use # to search by tag)"
 	si.CharLimit = 256
@@ -116,6 +158,9 @@ func initialModel() (model, error) {
 	emptyList := list.New([]list.Item{}, list.NewDefaultDelegate(), listWidth, listHeight)
 	emptyList.Title = "Select a file..."
 
+	detail := viewport.New(listWidth, listHeight)
+	keys.applyViewport(&detail)
+
 	return model{
 		notesList:        l,
 		confirmingDelete: false,
@@ -124,6 +169,7 @@ func initialModel() (model, error) {
 		editStage:        0,
 		editItem:         NoteItem{},
 		textInput:        ti,
+		messageInput:     ta,
 		newMsg:           "",
 		fileList:         emptyList,
 		selectedFile:     "",
@@ -132,140 +178,42 @@ func initialModel() (model, error) {
 		height:           0,
 		searchMode:       false,
 		searchInput:      si,
+		detail:           detail,
+		previews:         map[string]previewFile{},
+		keys:             keys,
+		help:             help.New(),
 		allItems:         all,
+		store:            s,
 	}, nil
 }
 
-func filterItems(all []NoteItem, rawQuery string) []list.Item {
-	raw := strings.TrimSpace(rawQuery)
-	if raw == "" {
-		out := make([]list.Item, len(all))
-		for i, n := range all {
-			out[i] = n
-		}
-		return out
-	}
-
-	terms := strings.Fields(raw)
-	var tagFilters []string
-	var textFilters []string
-	for _, t := range terms {
-		if strings.HasPrefix(t, "#") && len(t) > 1 {
-			tagFilters = append(tagFilters, strings.ToLower(t[1:]))
-		} else {
-			textFilters = append(textFilters, strings.ToLower(t))
-		}
-	}
-
-	var filtered []list.Item
-	for _, ni := range all {
-		matches := true
-		lowMsg := strings.ToLower(ni.Message)
-		lowFile := strings.ToLower(ni.File)
-
-		for _, tf := range textFilters {
-			if !strings.Contains(lowMsg, tf) && !strings.Contains(lowFile, tf) {
-				matches = false
-				break
-			}
-		}
-
-		if !matches {
-			continue
-		}
-
-		for _, tg := range tagFilters {
-			foundTag := false
-			for _, noteTag := range ni.Tags {
-				if strings.ToLower(noteTag) == tg {
-					foundTag = true
-					break
-				}
-			}
-			if !foundTag {
-				matches = false
-				break
-			}
-		}
-		if !matches {
-			continue
-		}
-
-		filtered = append(filtered, ni)
-	}
-
-	return filtered
+// fileItems lists the files a note can be linked to, skipping those ignored
+// by git, after a "(No File)" item.
+func fileItems(root string) []list.Item {
+	items := []list.Item{NoteItem{Note: Note{Message: "(No File)"}}}
+	files.Walk(root, func(rel string) error {
+		items = append(items, NoteItem{Note: Note{Message: rel}})
+		return nil
+	})
+	return items
 }
 
-func LoadAllNotes() ([]Note, error) {
-	root, err := os.Getwd()
+// filterItems returns the notes matching a search query. The query uses the
+// same language as notes list, so "#tag" filters by tag and other words
+// match the message or file.
+func filterItems(all []NoteItem, rawQuery string) ([]list.Item, error) {
+	q, err := query.Parse(rawQuery)
 	if err != nil {
 		return nil, err
 	}
 
-	notesPath := filepath.Join(root, ".notes", "notes.json")
-	if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-		return []Note{}, nil
-	}
-
-	data, err := os.ReadFile(notesPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var notes []Note
-	if err := json.Unmarshal(data, &notes); err != nil {
-		return nil, err
-	}
-
-	return notes, nil
-}
-
-func DeleteNoteByID(id string) error {
-	root, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	notesPath := filepath.Join(root, ".notes", "notes.json")
-	if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-		return fmt.Errorf("notes file does not exist")
-	}
-
-	data, err := os.ReadFile(notesPath)
-	if err != nil {
-		return err
-	}
-
-	var notes []Note
-	if err := json.Unmarshal(data, &notes); err != nil {
-		return err
-	}
-
-	var updated []Note
-	found := false
-	for _, n := range notes {
-		short := n.ID
-		if len(short) > 8 {
-			short = short[:8]
-		}
-		if short == id || n.ID == id {
-			found = true
-			continue
+	var filtered []list.Item
+	for _, ni := range all {
+		if q.Match(ni.Note) {
+			filtered = append(filtered, ni)
 		}
-		updated = append(updated, n)
-	}
-
-	if !found {
-		return fmt.Errorf("no note found with id %s", id)
 	}
-
-	out, err := json.MarshalIndent(updated, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(notesPath, out, 0644)
+	return filtered, nil
 }
 
 var (
@@ -289,9 +237,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
 		w := max(1, m.width-2)
-		h := max(1, m.height-4)
-		m.notesList.SetSize(w, h)
+		h := m.bodyHeight()
+		m.notesList.SetSize(m.listWidth(), h)
 		m.textInput.Width = max(1, m.width-6)
+		m.messageInput.SetWidth(max(1, m.width-10))
+		m.help.Width = m.width
 		if m.addStage == 2 {
 			m.fileList.SetSize(w, h)
 		}
@@ -301,60 +251,79 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.searchMode {
 			m.searchInput.Width = max(1, m.width-2)
 		}
+		if m.showDetail {
+			m = m.openDetail()
+		}
+		return m, nil
+
+	case editorClosedMsg:
+		// the file may have been changed in the editor
+		clear(m.previews)
+		if msg.err != nil {
+			return m, tea.Printf("editor failed: %v", msg.err)
+		}
 		return m, nil
 
 	case tea.KeyMsg:
-		key := msg.String()
+		pressed := msg.String()
 
 		if m.searchMode {
-			switch key {
-			case "esc", "ctrl+c":
+			switch {
+			case key.Matches(msg, m.keys.Cancel):
 				m.searchMode = false
+				m.searchErr = ""
 				items := make([]list.Item, len(m.allItems))
 				for i, ni := range m.allItems {
 					items[i] = ni
 				}
 				m.notesList.SetItems(items)
 				return m, nil
+			case key.Matches(msg, m.keys.ToggleFuzzy):
+				m.fuzzySearch = !m.fuzzySearch
+				return m.refilter(), nil
 			}
 
 			var cmd tea.Cmd
 			m.searchInput, cmd = m.searchInput.Update(msg)
-			query := m.searchInput.Value()
-			items := filterItems(m.allItems, query)
-			m.notesList.SetItems(items)
+			return m.refilter(), cmd
+		}
+
+		if m.showHelp {
+			if pressed == "ctrl+c" {
+				return m, tea.Quit
+			}
+			// any other key closes the help
+			m.showHelp = false
+			return m, nil
+		}
+
+		if m.showDetail {
+			if pressed == "ctrl+c" {
+				return m, tea.Quit
+			}
+			if key.Matches(msg, m.keys.Back, m.keys.View, m.keys.Quit) {
+				m.showDetail = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.detail, cmd = m.detail.Update(msg)
 			return m, cmd
 		}
 
 		if m.addStage > 0 {
-			switch key {
-			case "enter":
-				value := strings.TrimSpace(m.textInput.Value())
+			switch {
+			case key.Matches(msg, m.keys.Submit):
 				switch m.addStage {
 				case 1:
-					m.newMsg = value
+					m.newMsg = strings.TrimSpace(m.messageInput.Value())
+					m.messageInput.Blur()
 					m.addStage = 2
-					var items []list.Item
-					root, _ := os.Getwd()
-					filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-						if err != nil {
-							return err
-						}
-						if info.IsDir() && strings.HasPrefix(info.Name(), ".") {
-							return filepath.SkipDir
-						}
-						if !info.IsDir() {
-							rel, _ := filepath.Rel(root, path)
-							items = append(items, NoteItem{Message: rel})
-						}
-						return nil
-					})
-
-					items = append([]list.Item{NoteItem{Message: "(No File)"}}, items...)
+					items := fileItems(m.store.Root())
 					w := max(1, m.width-2)
 					h := max(1, m.height-4)
 					m.fileList = list.New(items, list.NewDefaultDelegate(), w, h)
-					m.fileList.Title = "Step 2/3: Select file (Enter to choose, Esc to cancel)"
+					m.keys.applyList(&m.fileList)
+					m.fileList.Title = fmt.Sprintf("Step 2/3: Select file (%s to choose, %s to cancel)", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 
 					return m, nil
 				case 2:
@@ -378,24 +347,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 						m.newTags = parts
 					}
-					if err := SaveNote(m.newMsg, m.selectedFile, 0, m.newTags); err != nil {
+					note := Note{Message: m.newMsg, Tags: m.newTags}
+					recordGitContext(m.store.Root(), &note)
+					relink(m.store.Root(), &note, m.selectedFile)
+					if _, err := m.store.Add(note); err != nil {
 						return m, tea.Printf("failed to save note: %v", err)
 					}
 					newModel, _ := initialModel()
 					newModel.width, newModel.height = m.width, m.height
-					w := max(1, m.width-2)
-					h := max(1, m.height-4)
-					newModel.notesList.SetSize(w, h)
+					newModel.help.Width = m.width
+					newModel.notesList.SetSize(newModel.listWidth(), newModel.bodyHeight())
 					newModel.addStage = 0
 					return newModel, nil
 				}
 
-			case "esc", "ctrl+c":
+			case key.Matches(msg, m.keys.Cancel):
 				m.addStage = 0
 				m.textInput.Blur()
+				m.messageInput.Blur()
 				return m, nil
 			}
 
+			if m.addStage == 1 {
+				var cmd tea.Cmd
+				m.messageInput, cmd = m.messageInput.Update(msg)
+				return m, cmd
+			}
+
 			if m.addStage == 2 {
 				updatedList, cmd := m.fileList.Update(msg)
 				m.fileList = updatedList
@@ -409,35 +387,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.editStage > 0 {
-			switch key {
-			case "enter":
-				value := strings.TrimSpace(m.textInput.Value())
+			switch {
+			case key.Matches(msg, m.keys.Submit):
 				switch m.editStage {
 				case 1:
-					m.editItem.Message = value
+					m.editItem.Message = strings.TrimSpace(m.messageInput.Value())
+					m.messageInput.Blur()
 					m.editStage = 2
 
-					var items []list.Item
-					root, _ := os.Getwd()
-					filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-						if err != nil {
-							return err
-						}
-						if info.IsDir() && strings.HasPrefix(info.Name(), ".") {
-							return filepath.SkipDir
-						}
-						if !info.IsDir() {
-							rel, _ := filepath.Rel(root, path)
-							items = append(items, NoteItem{Message: rel})
-						}
-						return nil
-					})
-
-					items = append([]list.Item{NoteItem{Message: "(No File)"}}, items...)
+					items := fileItems(m.store.Root())
 					w := max(1, m.width-2)
 					h := max(1, m.height-4)
 					m.fileList = list.New(items, list.NewDefaultDelegate(), w, h)
-					m.fileList.Title = "Step 2/3: Select file (Enter to choose, Esc to skip)"
+					m.keys.applyList(&m.fileList)
+					m.fileList.Title = fmt.Sprintf("Step 2/3: Select file (%s to choose, %s to skip)", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 
 					if m.editItem.File == "" {
 						m.fileList.Select(0)
@@ -477,40 +440,38 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.editItem.Tags = []string{}
 					}
 
-					root, _ := os.Getwd()
-					notesPath := filepath.Join(root, ".notes", "notes.json")
-					bytes, _ := os.ReadFile(notesPath)
-					var allNotes []Note
-					_ = json.Unmarshal(bytes, &allNotes)
-
-					for i := range allNotes {
-						if allNotes[i].ID == m.editItem.ID {
-							allNotes[i].Message = m.editItem.Message
-							allNotes[i].File = m.editItem.File
-							allNotes[i].Tags = m.editItem.Tags
-							allNotes[i].CreatedAt = m.editItem.CreatedAt
-							break
+					if note, err := m.store.Get(m.editItem.ID); err == nil {
+						note.Message = m.editItem.Message
+						if note.File != m.editItem.File {
+							relink(m.store.Root(), &note, m.editItem.File)
+						}
+						note.Tags = m.editItem.Tags
+						if err := m.store.Update(note); err != nil {
+							return m, tea.Printf("failed to update note: %v", err)
 						}
 					}
 
-					out, _ := json.MarshalIndent(allNotes, "", "  ")
-					_ = os.WriteFile(notesPath, out, 0644)
-
 					newModel, _ := initialModel()
 					newModel.width, newModel.height = m.width, m.height
-					w := max(1, m.width-2)
-					h := max(1, m.height-4)
-					newModel.notesList.SetSize(w, h)
+					newModel.help.Width = m.width
+					newModel.notesList.SetSize(newModel.listWidth(), newModel.bodyHeight())
 					newModel.editStage = 0
 					return newModel, nil
 				}
 
-			case "esc", "ctrl+c":
+			case key.Matches(msg, m.keys.Cancel):
 				m.editStage = 0
 				m.textInput.Blur()
+				m.messageInput.Blur()
 				return m, nil
 			}
 
+			if m.editStage == 1 {
+				var cmd tea.Cmd
+				m.messageInput, cmd = m.messageInput.Update(msg)
+				return m, cmd
+			}
+
 			if m.editStage == 2 {
 				updatedList, cmd := m.fileList.Update(msg)
 				m.fileList = updatedList
@@ -523,18 +484,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.confirmingDelete {
-			switch key {
-			case "y", "yes", "Y", "enter":
+			switch {
+			case key.Matches(msg, m.keys.ConfirmDelete):
 				item := m.notesList.Items()[m.deleteIndex].(NoteItem)
-				if err := DeleteNoteByID(item.ID); err != nil {
+				if err := m.store.Delete(item.ID); err != nil {
 					fmt.Fprintf(os.Stderr, "Error deleting note: %v\n", err)
 				}
 
 				newModel, _ := initialModel()
 				newModel.width, newModel.height = m.width, m.height
-				w := max(1, m.width-2)
-				h := max(1, m.height-4)
-				newModel.notesList.SetSize(w, h)
+				newModel.help.Width = m.width
+				newModel.notesList.SetSize(newModel.listWidth(), newModel.bodyHeight())
 				return newModel, nil
 
 			default:
@@ -545,42 +505,66 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		switch key {
-		case "q", "esc", "ctrl+c", "ctrl+q":
+		switch {
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
-		case "ctrl+a":
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+
+		case key.Matches(msg, m.keys.Add):
 			m.addStage = 1
 			m.newMsg = ""
 			m.selectedFile = ""
 			m.newTags = []string{}
-			m.textInput.SetValue("")
-			m.textInput.Placeholder = "Note message"
-			m.textInput.Focus()
-			m.textInput.Width = max(1, m.width-6)
-			return m, nil
+			m.messageInput.Reset()
+			m.messageInput.Placeholder = "Note message"
+			m.messageInput.SetWidth(max(1, m.width-10))
+			return m, m.messageInput.Focus()
 
-		case "ctrl+e":
+		case key.Matches(msg, m.keys.Edit):
 			idx := m.notesList.Index()
 			if idx >= 0 && idx < len(m.notesList.Items()) {
 				selected := m.notesList.Items()[idx].(NoteItem)
 				m.editItem = selected
 				m.editStage = 1
-				m.textInput.SetValue(selected.Message)
-				m.textInput.Placeholder = "Edit message (Enter to continue, Esc to cancel)"
-				m.textInput.Focus()
-				m.textInput.Width = max(1, m.width-6)
+				m.messageInput.Reset()
+				m.messageInput.SetValue(selected.Message)
+				m.messageInput.Placeholder = "Note message"
+				m.messageInput.SetWidth(max(1, m.width-10))
+				return m, m.messageInput.Focus()
 			}
 			return m, nil
 
-		case "delete", "ctrl+d":
+		case key.Matches(msg, m.keys.Delete):
 			idx := m.notesList.Index()
 			if idx >= 0 && idx < len(m.notesList.Items()) {
 				m.confirmingDelete = true
 				m.deleteIndex = idx
 			}
 
-		case "/", "ctrl+f":
+		case key.Matches(msg, m.keys.View):
+			if _, ok := m.notesList.SelectedItem().(NoteItem); ok {
+				m.showDetail = true
+				return m.openDetail(), nil
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Open):
+			idx := m.notesList.Index()
+			if idx >= 0 && idx < len(m.notesList.Items()) {
+				selected := m.notesList.Items()[idx].(NoteItem)
+				c, err := editorCommand(m.store.Root(), selected.Note)
+				if err != nil {
+					return m, tea.Printf("cannot open editor: %v", err)
+				}
+				// suspend the TUI while the editor runs in the terminal
+				return m, tea.ExecProcess(c, func(err error) tea.Msg { return editorClosedMsg{err} })
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Search):
 			m.searchMode = true
 			m.searchInput.SetValue("")
 			m.searchInput.Focus()
@@ -593,19 +577,63 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// openDetail renders the selected note into the detail view, sized to the
+// window.
+func (m model) openDetail() model {
+	item, ok := m.notesList.SelectedItem().(NoteItem)
+	if !ok {
+		m.showDetail = false
+		return m
+	}
+	m.detail.Width = max(1, m.width-2)
+	m.detail.Height = max(1, m.height-4)
+	m.detail.SetContent(renderDetail(item, m.detail.Width))
+	m.detail.GotoTop()
+	return m
+}
+
+// refilter applies the search bar to the list of notes.
+func (m model) refilter() model {
+	if m.fuzzySearch {
+		m.searchErr = ""
+		m.notesList.SetItems(fuzzyFilterItems(m.allItems, m.searchInput.Value()))
+		// the best match is first
+		m.notesList.Select(0)
+		return m
+	}
+
+	// keep the last results while the query is incomplete, e.g. an open
+	// parenthesis or quote
+	items, err := filterItems(m.allItems, m.searchInput.Value())
+	if err != nil {
+		m.searchErr = err.Error()
+		return m
+	}
+	m.searchErr = ""
+	m.notesList.SetItems(items)
+	return m
+}
+
 func (m model) View() string {
 
 	if m.searchMode {
-		bar := "Search: " + m.searchInput.View()
-		return fmt.Sprintf("%s\n\n%s\n\n(Enter to filter, Esc to clear)", bar, m.notesList.View())
+		mode := "exact"
+		if m.fuzzySearch {
+			mode = "fuzzy"
+		}
+		bar := fmt.Sprintf("Search (%s): %s", mode, m.searchInput.View())
+		if m.searchErr != "" {
+			bar += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Render(m.searchErr)
+		}
+		return fmt.Sprintf("%s\n\n%s\n\n(%s to switch between exact and fuzzy, %s to clear)", bar, m.mainView(), m.keys.ToggleFuzzy.Help().Key, m.keys.Cancel.Help().Key)
 	}
 
 	if m.addStage > 0 || m.editStage > 0 {
 		if m.addStage > 0 {
 			switch m.addStage {
 			case 1:
-				prompt := "Step 1/3: Enter note message (Enter to continue, Esc to cancel)\n\n"
-				raw := prompt + m.textInput.View()
+				prompt := fmt.Sprintf("Step 1/3: Enter note message (%s to continue, Alt+Enter for a new line, %s to cancel)\n\n", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
+				raw := prompt + m.messageInput.View()
 				wrap := lipgloss.NewStyle().MaxWidth(m.width - 6).Render(raw)
 				box := lipgloss.NewStyle().
 					Border(lipgloss.RoundedBorder(), true).
@@ -616,9 +644,9 @@ func (m model) View() string {
 					Render(wrap)
 				return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
 			case 2:
-				return "\n" + m.fileList.View() + "\n\n(Use ↑/↓, Enter to pick, Esc to cancel)"
+				return "\n" + m.fileList.View() + fmt.Sprintf("\n\n(Use %s/%s, %s to pick, %s to cancel)", m.keys.Up.Help().Key, m.keys.Down.Help().Key, m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 			case 3:
-				prompt := "Step 3/3: Enter tags (comma-separated, leave blank) (Enter to save, Esc to cancel)\n\n"
+				prompt := fmt.Sprintf("Step 3/3: Enter tags (comma-separated, leave blank) (%s to save, %s to cancel)\n\n", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 				raw := prompt + m.textInput.View()
 				wrap := lipgloss.NewStyle().MaxWidth(m.width - 6).Render(raw)
 				box := lipgloss.NewStyle().
@@ -635,8 +663,8 @@ func (m model) View() string {
 		if m.editStage > 0 {
 			switch m.editStage {
 			case 1:
-				prompt := "Edit message (Enter to continue, Esc to cancel)\n\n"
-				raw := prompt + m.textInput.View()
+				prompt := fmt.Sprintf("Edit message (%s to continue, Alt+Enter for a new line, %s to cancel)\n\n", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
+				raw := prompt + m.messageInput.View()
 				wrap := lipgloss.NewStyle().MaxWidth(m.width - 6).Render(raw)
 				box := lipgloss.NewStyle().
 					Border(lipgloss.RoundedBorder(), true).
@@ -647,9 +675,9 @@ func (m model) View() string {
 					Render(wrap)
 				return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
 			case 2:
-				return "\n" + m.fileList.View() + "\n\n(Use ↑/↓, Enter to choose, Esc to cancel)"
+				return "\n" + m.fileList.View() + fmt.Sprintf("\n\n(Use %s/%s, %s to choose, %s to cancel)", m.keys.Up.Help().Key, m.keys.Down.Help().Key, m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 			case 3:
-				prompt := "Edit tags (comma-separated, leave blank) (Enter to save, Esc to cancel)\n\n"
+				prompt := fmt.Sprintf("Edit tags (comma-separated, leave blank) (%s to save, %s to cancel)\n\n", m.keys.Submit.Help().Key, m.keys.Cancel.Help().Key)
 				raw := prompt + m.textInput.View()
 				wrap := lipgloss.NewStyle().MaxWidth(m.width - 6).Render(raw)
 				box := lipgloss.NewStyle().
@@ -664,6 +692,18 @@ func (m model) View() string {
 		}
 	}
 
+	if m.showHelp {
+		// leave room for the border and padding of the modal
+		h := m.help
+		h.Width = max(0, m.width-8)
+		content := "Keys\n\n" + h.FullHelpView(m.keys.FullHelp()) + "\n\nPress any key to close"
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modalStyle(content))
+	}
+
+	if m.showDetail {
+		return "\n" + m.detail.View() + "\n\n" + m.help.ShortHelpView([]key.Binding{m.keys.Up, m.keys.Down, m.keys.Back})
+	}
+
 	if m.confirmingDelete {
 		item := m.notesList.Items()[m.deleteIndex].(NoteItem)
 
@@ -672,14 +712,14 @@ func (m model) View() string {
 		lines = append(lines, fmt.Sprintf("\"%s\"", item.Message), "")
 
 		if item.File != "" {
-			lines = append(lines, fmt.Sprintf("File: %s:%d", item.File, item.Line))
+			lines = append(lines, fmt.Sprintf("File: %s", item.Location()))
 		}
 
 		if len(item.Tags) > 0 {
 			lines = append(lines, fmt.Sprintf("Tags: %s", strings.Join(item.Tags, ", ")))
 		}
 
-		lines = append(lines, "", "Press Y/Enter to confirm, any other key to cancel")
+		lines = append(lines, "", fmt.Sprintf("Press %s to confirm, any other key to cancel", m.keys.ConfirmDelete.Help().Key))
 
 		content := strings.Join(lines, "\n")
 
@@ -687,5 +727,5 @@ func (m model) View() string {
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal)
 	}
 
-	return "\n" + m.notesList.View() + "\n\n(Use Ctrl+D to remove, Ctrl+E to edit, Ctrl+A to add, Ctrl+F to search, Ctrl+Q to quit)"
+	return "\n" + m.mainView() + "\n\n" + m.help.ShortHelpView(m.keys.ShortHelp())
 }
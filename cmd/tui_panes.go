@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"spjoes/notes/highlight"
+)
+
+// splitMinWidth is the narrowest window that shows the detail and preview
+// panes next to the list; narrower windows show the list alone.
+const splitMinWidth = 90
+
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder(), true).
+			BorderForeground(lipgloss.Color("#5DAFF4")).
+			Padding(0, 1)
+	previewGutter = lipgloss.NewStyle().Foreground(lipgloss.Color("#767676"))
+	previewMarker = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD75F")).Bold(true)
+	previewHit    = lipgloss.NewStyle().Background(lipgloss.Color("#444444")).Foreground(lipgloss.Color("#FFFFFF"))
+)
+
+// previewFile is a file read for the preview pane.
+type previewFile struct {
+	lines  []string
+	tokens [][]highlight.Token
+	err    error
+}
+
+// split reports whether the window is wide enough for the side panes.
+func (m model) split() bool {
+	return m.width >= splitMinWidth
+}
+
+// listWidth returns the width of the note list.
+func (m model) listWidth() int {
+	if !m.split() {
+		return max(1, m.width-2)
+	}
+	return m.width * 2 / 5
+}
+
+// bodyHeight returns the height of the list and panes, leaving room for the
+// blank line above them and the help below.
+func (m model) bodyHeight() int {
+	return max(1, m.height-4)
+}
+
+// mainView renders the note list, with the detail and preview of the
+// selected note beside it when there is room.
+func (m model) mainView() string {
+	if !m.split() {
+		return m.notesList.View()
+	}
+
+	// the panes' borders and padding take two rows and four columns
+	width := m.width - m.listWidth() - 1
+	innerW := max(1, width-4)
+	detailH := max(1, m.bodyHeight()/2-2)
+	previewH := max(1, m.bodyHeight()-m.bodyHeight()/2-2)
+
+	var detail, preview string
+	if item, ok := m.notesList.SelectedItem().(NoteItem); ok {
+		detail = renderDetail(item, innerW)
+		preview = m.renderPreview(item, innerW, previewH)
+	} else {
+		detail = detailLabel.Render("No note selected")
+	}
+
+	right := lipgloss.JoinVertical(lipgloss.Left,
+		paneStyle.Width(innerW+2).Height(detailH).Render(clip(detail, innerW, detailH)),
+		paneStyle.Width(innerW+2).Height(previewH).Render(clip(preview, innerW, previewH)),
+	)
+	// pad the list to its width without wrapping its title
+	list := lipgloss.NewStyle().MaxWidth(m.listWidth()).MaxHeight(m.bodyHeight()).Render(m.notesList.View())
+	list = lipgloss.PlaceHorizontal(m.listWidth(), lipgloss.Left, list)
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, " ", right)
+}
+
+// clip cuts text to a box of width columns and height lines.
+func clip(text string, width, height int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(strings.Join(lines, "\n"))
+}
+
+// preview returns a file of the project, reading it on first use.
+func (m model) preview(file string) previewFile {
+	if p, ok := m.previews[file]; ok {
+		return p
+	}
+	var p previewFile
+	data, err := os.ReadFile(filepath.Join(m.store.Root(), filepath.FromSlash(file)))
+	if err != nil {
+		p.err = err
+	} else {
+		p.lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		p.tokens = highlight.Lines(highlight.ForFile(file), p.lines)
+	}
+	m.previews[file] = p
+	return p
+}
+
+// renderPreview renders the lines of a note's file around its line, with the
+// noted lines highlighted, in a pane width columns wide and height lines
+// high.
+func (m model) renderPreview(item NoteItem, width, height int) string {
+	if item.File == "" {
+		return detailLabel.Render("Not linked to a file")
+	}
+	header := detailLabel.Render(item.File)
+	height--
+
+	p := m.preview(item.File)
+	if p.err != nil {
+		return header + "\n" + detailLabel.Render(fmt.Sprintf("cannot read file: %v", p.err))
+	}
+	if item.Line == 0 {
+		item.Line = 1
+	}
+	if item.Line > len(p.lines) {
+		return header + "\n" + detailLabel.Render(fmt.Sprintf("line %d is past the end of the file (%d lines)", item.Line, len(p.lines)))
+	}
+
+	// centre the noted lines, or start at the first when they don't fit
+	first := item.Line - max(0, (height-(item.LastLine()-item.Line+1))/2)
+	first = max(1, min(first, len(p.lines)-height+1))
+	last := min(len(p.lines), first+height-1)
+
+	numWidth := len(fmt.Sprint(last))
+	textWidth := max(1, width-numWidth-3)
+	var b strings.Builder
+	b.WriteString(header)
+	for i := first; i <= last; i++ {
+		num := fmt.Sprintf("%*d", numWidth, i)
+		b.WriteString("\n")
+		if item.Covers(i) {
+			text, _ := truncateRunes(strings.ReplaceAll(p.lines[i-1], "\t", "    "), textWidth, nil)
+			b.WriteString(previewMarker.Render(num + " ▶ "))
+			b.WriteString(previewHit.Width(textWidth).Render(text))
+		} else {
+			b.WriteString(previewGutter.Render(num + " │ "))
+			b.WriteString(colorTokensTUI(p.tokens[i-1]))
+		}
+	}
+	return b.String()
+}
+
+// colorTokensTUI renders highlighted tokens with the TUI's styles.
+func colorTokensTUI(toks []highlight.Token) string {
+	var b strings.Builder
+	for _, t := range toks {
+		b.WriteString(tuiTheme.Token(t.Kind, strings.ReplaceAll(t.Text, "\t", "    ")))
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var webAddr string
+
+// webCmd represents the web command
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Browse the notes of the project in a web browser",
+	Long: `Serves a read-only web page listing the notes of the current project, for
+sharing your screen in reviews with people who don't live in the terminal.
+
+Notes can be filtered by tag, file or a query like in notes list. Selecting
+a note shows its message rendered as markdown and the file it refers to with
+the noted lines highlighted. The page follows changes to the notes as they
+happen, and its address keeps the filters and the selected note, so it can
+be shared or bookmarked.
+
+Nothing can be changed from the page; use notes serve for a writable API.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := openStore()
+		if err != nil {
+			fmt.Println("Error opening notes:", err)
+			return
+		}
+		api, err := newAPIServer(s, webAddr, nil)
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			return
+		}
+
+		runServer(webAddr, newWebServer(api), api, "Browse the notes of %s at http://%s (Ctrl+C to stop)\n")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(webCmd)
+
+	webCmd.Flags().StringVar(&webAddr, "addr", "127.0.0.1:7778", "Address to listen on")
+}
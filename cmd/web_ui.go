@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"spjoes/notes/anchor"
+	"spjoes/notes/highlight"
+	"spjoes/notes/markdown"
+)
+
+//go:embed webui
+var webAssets embed.FS
+
+// webMaxLines is the longest file the code view shows in full. Longer files
+// are cut to webExcerpt lines around the note.
+const (
+	webMaxLines = 2000
+	webExcerpt  = 200
+)
+
+// webView is a note as the web UI shows it: its message rendered and the
+// code it refers to.
+type webView struct {
+	Note    noteRecord `json:"note"`
+	Message string     `json:"message_html"`
+	Code    *webCode   `json:"code,omitempty"`
+	// CodeError says why a note on a file has no code view, e.g. because
+	// the file was deleted.
+	CodeError string `json:"code_error,omitempty"`
+}
+
+// webCode is the highlighted text of a file, or of an excerpt of it.
+type webCode struct {
+	File string `json:"file"`
+	// First is the number of the first line in Lines.
+	First int      `json:"first"`
+	Total int      `json:"total"`
+	Lines []string `json:"lines"`
+	// Notes are the lines of every note on the file, so the view can mark
+	// them all.
+	Notes []webSpan `json:"notes"`
+}
+
+type webSpan struct {
+	ID      string `json:"id"`
+	Line    int    `json:"line"`
+	EndLine int    `json:"end_line"`
+}
+
+// webServer serves the read-only web UI, with the read endpoints of the
+// API under /api.
+type webServer struct {
+	api *apiServer
+	mux *http.ServeMux
+}
+
+func newWebServer(api *apiServer) *webServer {
+	s := &webServer{api: api, mux: http.NewServeMux()}
+	assets, _ := fs.Sub(webAssets, "webui")
+	s.mux.Handle("GET /", http.FileServerFS(assets))
+	s.mux.HandleFunc("GET /api/project", s.project)
+	s.mux.HandleFunc("GET /api/notes", api.listNotes)
+	s.mux.HandleFunc("GET /api/notes/{id}", api.getNote)
+	s.mux.HandleFunc("GET /api/notes/{id}/view", s.view)
+	s.mux.HandleFunc("GET /api/events", api.events)
+	return s
+}
+
+func (s *webServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.api.allowHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed; use localhost or the address given with --addr", r.Host))
+		return
+	}
+	h := w.Header()
+	// messages are escaped when rendered; the policy is a second line of
+	// defence against markup in them
+	h.Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'")
+	h.Set("X-Content-Type-Options", "nosniff")
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *webServer) project(w http.ResponseWriter, r *http.Request) {
+	body, _ := json.Marshal(map[string]string{"name": filepath.Base(s.api.root), "root": s.api.root})
+	writeBody(w, r, http.StatusOK, etagOf(body), body)
+}
+
+func (s *webServer) view(w http.ResponseWriter, r *http.Request) {
+	stored, err := s.api.store.Get(r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	locator := anchor.NewLocator(s.api.root)
+	n, status := locate(locator, stored)
+	v := webView{
+		Note:    newNoteRecord(locatedNote{Note: n, Status: status, StoredLine: stored.Line}),
+		Message: markdown.HTML(n.Message),
+	}
+	if n.File != "" {
+		v.Code, err = s.code(locator, n)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			v.CodeError = "The file no longer exists."
+		case err != nil:
+			v.CodeError = err.Error()
+		}
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeBody(w, r, http.StatusOK, etagOf(body), body)
+}
+
+// code returns the highlighted file of a note, with the lines of every
+// note on it.
+func (s *webServer) code(locator *anchor.Locator, n Note) (*webCode, error) {
+	if strings.HasPrefix(n.File, "../") || filepath.IsAbs(n.File) {
+		return nil, fs.ErrPermission
+	}
+	lines, err := anchor.ReadLines(filepath.Join(s.api.root, filepath.FromSlash(n.File)))
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := 1, len(lines)
+	if len(lines) > webMaxLines && n.Line > 0 {
+		first = max(1, min(n.Line, len(lines))-webExcerpt)
+		last = min(len(lines), n.LastLine()+webExcerpt)
+	} else if len(lines) > webMaxLines {
+		last = webMaxLines
+	}
+	code := &webCode{File: n.File, First: first, Total: len(lines), Lines: []string{}, Notes: []webSpan{}}
+	// highlight from the top, so comments opened above the excerpt count
+	for _, toks := range highlight.Lines(highlight.ForFile(n.File), lines[:last])[first-1:] {
+		code.Lines = append(code.Lines, highlight.HTML(toks))
+	}
+
+	others, err := s.api.store.Query(func(o Note) bool { return o.File == n.File && o.Line > 0 })
+	if err != nil {
+		return nil, err
+	}
+	for _, stored := range others {
+		if o, status := locate(locator, stored); status != anchor.StatusOrphaned {
+			code.Notes = append(code.Notes, webSpan{ID: o.ID, Line: o.Line, EndLine: o.LastLine()})
+		}
+	}
+	return code, nil
+}
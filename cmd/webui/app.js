@@ -0,0 +1,339 @@
+// The notes web UI: the notes of the project filtered through the API, and
+// the selected note with the code it refers to. The filters and the selected
+// note live in the address, so a view can be shared or bookmarked.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+const state = { q: "", tag: "", file: "", note: "" };
+let notes = [];
+// scrolled is the note whose code was last scrolled to, so live updates
+// don't move the page under the reader.
+let scrolled = "";
+
+// el creates an element. Children are elements or strings, which are set
+// as text and never parsed as HTML.
+function el(tag, className, ...children) {
+  const e = document.createElement(tag);
+  if (className) e.className = className;
+  for (const c of children) {
+    if (c !== null && c !== undefined && c !== "") e.append(c);
+  }
+  return e;
+}
+
+async function getJSON(url) {
+  const res = await fetch(url, { cache: "no-cache" });
+  const body = await res.json();
+  if (!res.ok) {
+    const err = new Error(body.error || res.statusText);
+    err.status = res.status;
+    throw err;
+  }
+  return body;
+}
+
+function readHash() {
+  const params = new URLSearchParams(location.hash.slice(1));
+  for (const key of Object.keys(state)) state[key] = params.get(key) || "";
+  $("query").value = state.q;
+}
+
+function hashFor(changes) {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries({ ...state, ...changes })) {
+    if (value) params.set(key, value);
+  }
+  const hash = params.toString();
+  return hash ? "#" + hash : location.pathname;
+}
+
+function writeHash() {
+  history.replaceState(null, "", hashFor({}));
+}
+
+// authorName drops the email of a git author, like notes list does.
+function authorName(author) {
+  return author.replace(/\s*<[^>]*>$/, "");
+}
+
+function firstLine(message) {
+  return message.split("\n")[0].replace(/^#+\s*/, "");
+}
+
+function noteLocation(n) {
+  if (!n.file) return "";
+  if (!n.line) return n.file;
+  return n.end_line > n.line ? `${n.file}:${n.line}-${n.end_line}` : `${n.file}:${n.line}`;
+}
+
+function statusBadge(n) {
+  if (n.status === "moved") return el("span", "badge moved", `moved from line ${n.stored_line}`);
+  if (n.status === "orphaned") return el("span", "badge orphaned", "orphaned");
+  return null;
+}
+
+function tagChips(tags) {
+  const chips = el("span", "tags");
+  for (const tag of tags) {
+    const chip = el("span", "tag", tag);
+    chip.title = `Show notes tagged ${tag}`;
+    chip.addEventListener("click", (e) => {
+      e.preventDefault();
+      e.stopPropagation();
+      setFilter("tag", tag);
+    });
+    chips.append(chip);
+  }
+  return tags.length ? chips : null;
+}
+
+// fillSelect replaces the options of a filter, keeping its selection.
+function fillSelect(select, all, values, selected) {
+  select.replaceChildren(el("option", "", all));
+  select.firstChild.value = "";
+  if (selected && !values.includes(selected)) values = [selected, ...values];
+  for (const v of values) {
+    const option = el("option", "", v);
+    option.value = v;
+    select.append(option);
+  }
+  select.value = selected;
+}
+
+async function loadOptions() {
+  const all = await getJSON("api/notes");
+  const tags = [...new Set(all.flatMap((n) => n.tags))].sort();
+  const files = [...new Set(all.map((n) => n.file).filter(Boolean))].sort();
+  fillSelect($("tag"), "All tags", tags, state.tag);
+  fillSelect($("file"), "All files", files, state.file);
+}
+
+async function loadList() {
+  const params = new URLSearchParams();
+  if (state.q) params.set("q", state.q);
+  if (state.tag) params.set("tag", state.tag);
+  if (state.file) params.set("file", state.file);
+  const list = $("list");
+  try {
+    notes = await getJSON("api/notes?" + params);
+  } catch (err) {
+    notes = [];
+    list.replaceChildren(el("p", "none", err.message));
+    return;
+  }
+  renderList();
+}
+
+function renderList() {
+  const list = $("list");
+  const count = notes.length === 1 ? "1 note" : `${notes.length} notes`;
+  list.replaceChildren(el("p", "count", count));
+  if (notes.length === 0) {
+    list.append(el("p", "none", "No notes match the filters."));
+    return;
+  }
+  for (const n of notes) {
+    const item = el(
+      "a",
+      "item",
+      el("div", "title", el("span", "id", n.short_id), " ", firstLine(n.message)),
+      el("div", "where", noteLocation(n), " ", statusBadge(n)),
+      tagChips(n.tags),
+    );
+    item.href = hashFor({ note: n.short_id });
+    if (n.short_id === state.note || n.id === state.note) item.classList.add("selected");
+    item.addEventListener("click", (e) => {
+      if (e.metaKey || e.ctrlKey || e.shiftKey) return;
+      e.preventDefault();
+      select(n.short_id);
+    });
+    list.append(item);
+  }
+}
+
+function select(id) {
+  state.note = id;
+  writeHash();
+  renderList();
+  loadView();
+}
+
+function setFilter(key, value) {
+  state[key] = value;
+  writeHash();
+  loadOptions();
+  loadList();
+}
+
+async function loadView() {
+  const detail = $("detail");
+  if (!state.note) {
+    detail.replaceChildren(el("p", "empty", "Select a note to see it here."));
+    return;
+  }
+  let view;
+  try {
+    view = await getJSON(`api/notes/${encodeURIComponent(state.note)}/view`);
+  } catch (err) {
+    const text = err.status === 404 ? `Note ${state.note} was deleted.` : err.message;
+    detail.replaceChildren(el("p", "empty", text));
+    return;
+  }
+  renderView(view);
+}
+
+function renderView(view) {
+  const n = view.note;
+  const meta = el("div", "meta", el("span", "id", n.id), statusBadge(n), tagChips(n.tags));
+  if (n.author) meta.append(el("span", "", authorName(n.author)));
+  if (n.branch || n.commit) {
+    meta.append(el("span", "", [n.branch, n.commit && n.commit.slice(0, 7)].filter(Boolean).join(" @ ")));
+  }
+  meta.append(el("span", "", new Date(n.created_at).toLocaleString()));
+
+  const message = el("div", "message");
+  // rendered and escaped by the server
+  message.innerHTML = view.message_html;
+
+  const parts = [meta, message];
+  if (n.file) {
+    const title = el("h2", "code-title", n.file);
+    if (n.line) title.append(" ", el("span", "range", n.end_line > n.line ? `lines ${n.line}–${n.end_line}` : `line ${n.line}`));
+    parts.push(title);
+    if (view.code_error) {
+      parts.push(el("p", "code-error", view.code_error));
+    } else if (view.code) {
+      parts.push(codeView(view.code, n));
+    }
+  }
+  $("detail").replaceChildren(...parts);
+
+  const first = $("detail").querySelector("tr.noted");
+  if (first && scrolled !== n.id) first.scrollIntoView({ block: "center" });
+  scrolled = n.id;
+}
+
+// codeView renders the file of note n, highlighting its lines and marking
+// the lines of other notes, which select them when clicked.
+function codeView(code, n) {
+  const body = el("tbody");
+  const gap = (from, to) => {
+    const row = el("tr", "gap", el("td", "num", "…"), el("td", "", `lines ${from}–${to} not shown`));
+    body.append(row);
+  };
+  if (code.first > 1) gap(1, code.first - 1);
+
+  const noted = n.status !== "orphaned" && n.line > 0;
+  code.lines.forEach((html, i) => {
+    const num = code.first + i;
+    const line = el("td");
+    // highlighted and escaped by the server
+    line.innerHTML = html;
+    const row = el("tr", "", el("td", "num", String(num)), line);
+    if (noted && num >= n.line && num <= n.end_line) {
+      row.classList.add("noted");
+    } else {
+      const other = code.notes.find((o) => o.id !== n.id && num >= o.line && num <= o.end_line);
+      if (other) {
+        row.classList.add("other");
+        row.title = "Show the note on this line";
+        row.addEventListener("click", () => select(other.id.slice(0, 8)));
+      }
+    }
+    body.append(row);
+  });
+
+  const last = code.first + code.lines.length - 1;
+  if (last < code.total) gap(last + 1, code.total);
+  return el("div", "code", el("table", "", body));
+}
+
+async function refresh() {
+  await Promise.all([loadOptions(), loadList(), loadView()]);
+}
+
+// follow reloads the page's data whenever a note changes.
+function follow() {
+  const status = $("status");
+  let pending = null;
+  // changes made while the stream was down are not replayed
+  let reconnecting = false;
+  const events = new EventSource("api/events");
+  events.onopen = () => {
+    status.className = "status live";
+    status.textContent = "live";
+    if (reconnecting) refresh();
+    reconnecting = false;
+  };
+  events.onerror = () => {
+    status.className = "status offline";
+    status.textContent = "offline";
+    reconnecting = true;
+  };
+  for (const kind of ["created", "updated", "deleted"]) {
+    events.addEventListener(kind, () => {
+      clearTimeout(pending);
+      pending = setTimeout(refresh, 150);
+    });
+  }
+}
+
+function moveSelection(step) {
+  if (notes.length === 0) return;
+  const i = notes.findIndex((n) => n.short_id === state.note || n.id === state.note);
+  const next = notes[Math.min(notes.length - 1, Math.max(0, i < 0 ? 0 : i + step))];
+  select(next.short_id);
+  $("list").querySelector(".item.selected")?.scrollIntoView({ block: "nearest" });
+}
+
+function init() {
+  readHash();
+
+  let typing = null;
+  $("query").addEventListener("input", () => {
+    clearTimeout(typing);
+    typing = setTimeout(() => {
+      state.q = $("query").value.trim();
+      writeHash();
+      loadList();
+    }, 300);
+  });
+  $("filters").addEventListener("submit", (e) => e.preventDefault());
+  $("tag").addEventListener("change", () => setFilter("tag", $("tag").value));
+  $("file").addEventListener("change", () => setFilter("file", $("file").value));
+  $("clear").addEventListener("click", () => {
+    state.q = state.tag = state.file = "";
+    $("query").value = "";
+    writeHash();
+    loadOptions();
+    loadList();
+  });
+
+  document.addEventListener("keydown", (e) => {
+    if (e.target.closest("input, select, textarea") || e.metaKey || e.ctrlKey || e.altKey) return;
+    if (e.key === "ArrowDown" || e.key === "j") {
+      e.preventDefault();
+      moveSelection(1);
+    } else if (e.key === "ArrowUp" || e.key === "k") {
+      e.preventDefault();
+      moveSelection(-1);
+    } else if (e.key === "/") {
+      e.preventDefault();
+      $("query").focus();
+    }
+  });
+  window.addEventListener("hashchange", () => {
+    readHash();
+    refresh();
+  });
+
+  getJSON("api/project").then((p) => {
+    $("project").textContent = p.name;
+    document.title = `${p.name} · notes`;
+  });
+  refresh();
+  follow();
+}
+
+init();
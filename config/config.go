@@ -0,0 +1,65 @@
+// Package config reads the per-user configuration of notes, kept in
+// notes/config.json under the user configuration directory (for example
+// ~/.config/notes/config.json on Linux). Settings that belong to a project,
+// such as its storage format, live in the project's .notes/config.json
+// instead.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the per-user configuration.
+type Config struct {
+	// Editor is the command used to open files, e.g. "nvim" or
+	// "code --wait". It takes precedence over $VISUAL and $EDITOR.
+	Editor string `json:"editor,omitempty"`
+	// Editors overrides or adds the arguments used to open a file at a
+	// line, keyed by the name of the editor's executable. See the editor
+	// package for the placeholders.
+	Editors map[string]string `json:"editors,omitempty"`
+	// Keymap is the preset of TUI key bindings: "default", "vim" or
+	// "emacs".
+	Keymap string `json:"keymap,omitempty"`
+	// Keys overrides TUI key bindings of the preset, keyed by action, e.g.
+	// {"add": ["n"], "quit": ["q", "ctrl+c"]}.
+	Keys map[string][]string `json:"keys,omitempty"`
+}
+
+// Path returns the location of the user configuration file. $NOTES_CONFIG
+// overrides it.
+func Path() (string, error) {
+	if path := os.Getenv("NOTES_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notes", "config.json"), nil
+}
+
+// Load reads the user configuration, returning an empty one when there is
+// none.
+func Load() (Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,195 @@
+// Package editor launches the user's text editor at a position in a file.
+//
+// Each editor takes the position in its own way, so the arguments are
+// built from a template chosen by the name of the editor's executable.
+// Templates are split into arguments like a shell command line and may use
+// the placeholders {file}, {line} and {column}.
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Templates holds the arguments of known editors, by executable name.
+var Templates = map[string]string{
+	"vi":            "+{line} {file}",
+	"vim":           `"+call cursor({line},{column})" {file}`,
+	"nvim":          `"+call cursor({line},{column})" {file}`,
+	"gvim":          `"+call cursor({line},{column})" {file}`,
+	"mvim":          `"+call cursor({line},{column})" {file}`,
+	"emacs":         "+{line}:{column} {file}",
+	"emacsclient":   "+{line}:{column} {file}",
+	"nano":          "+{line},{column} {file}",
+	"micro":         "+{line}:{column} {file}",
+	"kak":           "+{line}:{column} {file}",
+	"gedit":         "+{line}:{column} {file}",
+	"hx":            "{file}:{line}:{column}",
+	"helix":         "{file}:{line}:{column}",
+	"subl":          "{file}:{line}:{column}",
+	"zed":           "{file}:{line}:{column}",
+	"code":          "--goto {file}:{line}:{column}",
+	"code-insiders": "--goto {file}:{line}:{column}",
+	"codium":        "--goto {file}:{line}:{column}",
+	"cursor":        "--goto {file}:{line}:{column}",
+	"mate":          "-l {line}:{column} {file}",
+	"kate":          "--line {line} --column {column} {file}",
+	"idea":          "--line {line} --column {column} {file}",
+	"goland":        "--line {line} --column {column} {file}",
+	"pycharm":       "--line {line} --column {column} {file}",
+	"webstorm":      "--line {line} --column {column} {file}",
+	"notepad++":     "-n{line} -c{column} {file}",
+}
+
+// defaultTemplate opens the file of editors that are not known.
+const defaultTemplate = "{file}"
+
+// Find returns the editor command to use: configured when set, then
+// $VISUAL, then $EDITOR, then a platform default.
+func Find(configured string) string {
+	for _, e := range []string{configured, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if strings.TrimSpace(e) != "" {
+			return e
+		}
+	}
+	return fallback
+}
+
+// Name returns the executable name of an editor command, e.g. "code" for
+// "/usr/bin/code --wait".
+func Name(command string) string {
+	args, err := SplitArgs(command)
+	if err != nil || len(args) == 0 {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(args[0]))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// Command returns the command that opens file at line and column in the
+// editor. Lines and columns start at 1; zero means the start of the file or
+// line. overrides take precedence over Templates.
+func Command(editorCmd string, overrides map[string]string, file string, line, column int) (*exec.Cmd, error) {
+	base, err := SplitArgs(editorCmd)
+	if err != nil {
+		return nil, fmt.Errorf("editor %q: %w", editorCmd, err)
+	}
+	if len(base) == 0 {
+		return nil, fmt.Errorf("no editor configured")
+	}
+
+	name := Name(editorCmd)
+	tmpl, ok := overrides[name]
+	if !ok {
+		tmpl, ok = Templates[name]
+	}
+	if !ok {
+		tmpl = defaultTemplate
+	}
+
+	args, err := Expand(tmpl, file, line, column)
+	if err != nil {
+		return nil, fmt.Errorf("template for %s: %w", name, err)
+	}
+	return exec.Command(base[0], append(base[1:], args...)...), nil
+}
+
+// Expand splits a template into arguments and fills in its placeholders.
+func Expand(tmpl, file string, line, column int) ([]string, error) {
+	args, err := SplitArgs(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	r := strings.NewReplacer(
+		"{file}", file,
+		"{line}", strconv.Itoa(max(line, 1)),
+		"{column}", strconv.Itoa(max(column, 1)),
+	)
+	for i, a := range args {
+		args[i] = r.Replace(a)
+	}
+	return args, nil
+}
+
+// SplitArgs splits a command line into arguments. Arguments are separated
+// by spaces and may be quoted with single or double quotes.
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// EditText lets the user edit text in the editor and returns the result.
+// The text is written to a temporary file named after pattern (see
+// os.CreateTemp), and the editor is opened at line. The editor must not
+// return before the user is done, e.g. "code --wait" rather than "code".
+//
+// When the editor fails the temporary file is kept and its path is part of
+// the error, so nothing the user wrote is lost.
+func EditText(editorCmd string, overrides map[string]string, pattern, text string, line int) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	c, err := Command(editorCmd, overrides, path, line, 1)
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w (your text is in %s)", Name(editorCmd), err, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	os.Remove(path)
+	return string(data), nil
+}
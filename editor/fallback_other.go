@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package editor
+
+// fallback is the editor used when none is configured.
+const fallback = "vi"
@@ -0,0 +1,97 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// pattern is one line of a .gitignore file.
+type pattern struct {
+	// base is the directory of the .gitignore file, relative to the root.
+	base string
+	glob string
+	// negate re-includes what earlier patterns excluded ("!pattern").
+	negate bool
+	// dirOnly matches directories only ("pattern/").
+	dirOnly bool
+}
+
+// Ignore holds the .gitignore patterns that apply to a directory. The
+// patterns of deeper files come last, so they take precedence.
+type Ignore struct {
+	patterns []pattern
+}
+
+// parsePattern parses a line of a .gitignore file in directory base.
+func parsePattern(base, line string) (pattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+	p := pattern{base: base}
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		p.negate = true
+		line = rest
+	}
+	// a backslash escapes a leading "#" or "!"
+	line = strings.TrimPrefix(line, `\`)
+	if rest, ok := strings.CutSuffix(line, "/"); ok {
+		p.dirOnly = true
+		line = rest
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// a pattern with a slash is relative to the .gitignore file; one
+	// without matches a name at any depth
+	if strings.Contains(line, "/") {
+		p.glob = strings.TrimPrefix(line, "/")
+	} else {
+		p.glob = "**/" + line
+	}
+	return p, true
+}
+
+// With returns the patterns of ig followed by those of the .gitignore-style
+// file at path, which applies to directory base relative to the root. A
+// missing file adds nothing.
+func (ig *Ignore) With(path, base string) *Ignore {
+	f, err := os.Open(path)
+	if err != nil {
+		return ig
+	}
+	defer f.Close()
+
+	next := &Ignore{patterns: append([]pattern(nil), ig.patterns...)}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if p, ok := parsePattern(base, sc.Text()); ok {
+			next.patterns = append(next.patterns, p)
+		}
+	}
+	return next
+}
+
+// Match reports whether a slash-separated path relative to the root is
+// ignored. The last pattern that matches decides.
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range ig.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if p.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, p.base+"/"); !ok {
+				continue
+			}
+		}
+		if Glob(p.glob, sub) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,55 @@
+// Package files lists the files of a project the way git sees them: files
+// and directories excluded by .gitignore files or .git/info/exclude are
+// skipped, as are the .git and .notes directories.
+package files
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// skipped are directories never walked, whatever the ignore files say.
+var skipped = map[string]bool{".git": true, ".notes": true}
+
+// Walk calls fn with the slash-separated path, relative to root, of every
+// regular file of the project that is not ignored, in lexical order. An
+// error returned by fn stops the walk.
+func Walk(root string, fn func(rel string) error) error {
+	ig := (&Ignore{}).With(filepath.Join(root, ".git", "info", "exclude"), "")
+	return walkDir(root, "", ig, fn)
+}
+
+func walkDir(root, rel string, ig *Ignore, fn func(rel string) error) error {
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	ig = ig.With(filepath.Join(dir, ".gitignore"), rel)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if rel == "" {
+			return err
+		}
+		// unreadable subdirectories are skipped like ignored ones
+		return nil
+	}
+	for _, e := range entries {
+		p := path.Join(rel, e.Name())
+		switch {
+		case e.IsDir():
+			if skipped[e.Name()] || ig.Match(p, true) {
+				continue
+			}
+			if err := walkDir(root, p, ig, fn); err != nil {
+				return err
+			}
+		case e.Type().IsRegular():
+			if ig.Match(p, false) {
+				continue
+			}
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package gitinfo
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Context is the git state a note was written in.
+type Context struct {
+	Commit string
+	Branch string
+	// Author is "Name <email>", or whichever of the two is configured.
+	Author string
+}
+
+// Current returns the git context of dir. It asks git when it is installed
+// and otherwise reads the .git directory directly. It returns
+// ErrNotRepository outside a repository.
+func Current(dir string) (Context, error) {
+	if _, err := Run(dir, "rev-parse", "--git-dir"); err != nil {
+		if errors.Is(err, ErrNotRepository) {
+			return readDotGit(dir)
+		}
+		return Context{}, err
+	}
+
+	var ctx Context
+	// HEAD fails in a repository without commits; keep the rest
+	ctx.Commit, _ = Head(dir)
+	ctx.Branch, _ = Run(dir, "symbolic-ref", "--short", "-q", "HEAD")
+	name, _ := Run(dir, "config", "user.name")
+	email, _ := Run(dir, "config", "user.email")
+	ctx.Author = formatAuthor(name, email)
+	return ctx, nil
+}
+
+func formatAuthor(name, email string) string {
+	switch {
+	case name != "" && email != "":
+		return name + " <" + email + ">"
+	case email != "":
+		return "<" + email + ">"
+	}
+	return name
+}
+
+// AuthorName returns the name part of an author, or the address when there
+// is no name.
+func AuthorName(author string) string {
+	name, email, found := strings.Cut(author, "<")
+	if name = strings.TrimSpace(name); name != "" || !found {
+		return name
+	}
+	return strings.TrimSuffix(email, ">")
+}
+
+// readDotGit reads the git context of dir without the git binary.
+func readDotGit(dir string) (Context, error) {
+	gitDir, err := findGitDir(dir)
+	if err != nil {
+		return Context{}, err
+	}
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolve(gitDir, strings.TrimSpace(string(data)))
+	}
+
+	var ctx Context
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return Context{}, err
+	}
+	ref, isRef := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if isRef {
+		ctx.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		ctx.Commit = readRef(commonDir, ref)
+	} else {
+		ctx.Commit = ref
+	}
+
+	name, email := readUser(filepath.Join(commonDir, "config"))
+	if home, err := os.UserHomeDir(); err == nil && (name == "" || email == "") {
+		globalName, globalEmail := readUser(filepath.Join(home, ".gitconfig"))
+		if name == "" {
+			name = globalName
+		}
+		if email == "" {
+			email = globalEmail
+		}
+	}
+	ctx.Author = formatAuthor(name, email)
+	return ctx, nil
+}
+
+// findGitDir walks up from dir to the .git directory, following the
+// "gitdir:" pointer used by worktrees and submodules.
+func findGitDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ".git")
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.IsDir() {
+				return path, nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			if target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: "); ok {
+				return resolve(dir, target), nil
+			}
+			return "", ErrNotRepository
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+func readRef(gitDir, ref string) string {
+	if data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sha, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return sha
+		}
+	}
+	return ""
+}
+
+// readUser returns user.name and user.email from a git config file.
+func readUser(path string) (name, email string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(line, "[] "))
+			continue
+		}
+		if section != "user" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			name = value
+		case "email":
+			email = value
+		}
+	}
+	return name, email
+}
+
+func resolve(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
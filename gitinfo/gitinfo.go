@@ -0,0 +1,149 @@
+// Package gitinfo reads the git state of a project: the current commit and
+// how files moved and changed between two commits.
+package gitinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrNotRepository is returned when a directory is not inside a git work
+// tree, or git is not installed.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Run runs git with args in dir and returns its trimmed standard output.
+func Run(dir string, args ...string) (string, error) {
+	c := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%w: %v", ErrNotRepository, err)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not a git repository") {
+			return "", ErrNotRepository
+		}
+		if msg == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Head returns the SHA of the commit checked out in dir.
+func Head(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--verify", "-q", "HEAD")
+}
+
+// Prefix returns the path of dir relative to the root of its work tree, with
+// a trailing slash, or "" at the root.
+func Prefix(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--show-prefix")
+}
+
+// HooksDir returns the directory git runs hooks from.
+func HooksDir(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--path-format=absolute", "--git-path", "hooks")
+}
+
+// Renames returns the files renamed between the commits from and to, mapping
+// old paths to new paths relative to the work tree root. Deleted files map to
+// "".
+func Renames(dir, from, to string) (map[string]string, error) {
+	out, err := Run(dir, "diff", "--no-color", "-M", "--name-status", "-z", "--diff-filter=RD", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	renames := map[string]string{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		switch {
+		case strings.HasPrefix(status, "R") && i+2 < len(fields):
+			renames[fields[i+1]] = fields[i+2]
+			i += 2
+		case status == "D" && i+1 < len(fields):
+			renames[fields[i+1]] = ""
+			i++
+		}
+	}
+	return renames, nil
+}
+
+// Hunk is a changed region of a file, in the form of a unified diff header.
+type Hunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+}
+
+// Hunks returns the changed regions of a file between the commits from and
+// to, where the file was called oldPath in from and newPath in to.
+func Hunks(dir, from, to, oldPath, newPath string) ([]Hunk, error) {
+	out, err := Run(dir, "diff", "--no-color", "-M", "-U0", from+":"+oldPath, to+":"+newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var hunks []Hunk
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "@@ ") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 3 {
+			continue
+		}
+		oldStart, oldCount := parseRange(strings.TrimPrefix(parts[1], "-"))
+		newStart, newCount := parseRange(strings.TrimPrefix(parts[2], "+"))
+		hunks = append(hunks, Hunk{oldStart, oldCount, newStart, newCount})
+	}
+	return hunks, nil
+}
+
+func parseRange(s string) (int, int) {
+	startStr, countStr, found := strings.Cut(s, ",")
+	start, _ := strconv.Atoi(startStr)
+	count := 1
+	if found {
+		count, _ = strconv.Atoi(countStr)
+	}
+	return start, count
+}
+
+// MapLine returns where the 1-based line of the old file ended up after the
+// changes in hunks. Lines inside a changed region map to the start of the
+// replacement; changed reports whether that happened.
+func MapLine(hunks []Hunk, line int) (newLine int, changed bool) {
+	offset := 0
+	for _, h := range hunks {
+		if h.OldCount == 0 {
+			// pure insertion after line OldStart
+			if line > h.OldStart {
+				offset += h.NewCount
+				continue
+			}
+			break
+		}
+		if line < h.OldStart {
+			break
+		}
+		if line >= h.OldStart+h.OldCount {
+			offset += h.NewCount - h.OldCount
+			continue
+		}
+		if h.NewCount == 0 {
+			return max(1, h.NewStart), true
+		}
+		return h.NewStart + min(line-h.OldStart, h.NewCount-1), true
+	}
+	return line + offset, false
+}
@@ -0,0 +1,58 @@
+package gitinfo
+
+import "testing"
+
+func TestMapLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		hunks   []Hunk
+		line    int
+		want    int
+		changed bool
+	}{
+		{"no changes", nil, 7, 7, false},
+		{"change below", []Hunk{{OldStart: 10, OldCount: 2, NewStart: 10, NewCount: 5}}, 7, 7, false},
+		{"lines inserted above", []Hunk{{OldStart: 3, OldCount: 0, NewStart: 4, NewCount: 2}}, 7, 9, false},
+		{"inserted right after the line", []Hunk{{OldStart: 7, OldCount: 0, NewStart: 8, NewCount: 2}}, 7, 7, false},
+		{"inserted at the top", []Hunk{{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 3}}, 1, 4, false},
+		{"lines removed above", []Hunk{{OldStart: 2, OldCount: 3, NewStart: 1, NewCount: 0}}, 7, 4, false},
+		{"lines replaced above", []Hunk{{OldStart: 2, OldCount: 2, NewStart: 2, NewCount: 5}}, 7, 10, false},
+		{
+			"several hunks above",
+			[]Hunk{
+				{OldStart: 1, OldCount: 0, NewStart: 2, NewCount: 1},
+				{OldStart: 3, OldCount: 2, NewStart: 4, NewCount: 0},
+			},
+			7, 6, false,
+		},
+		{"first line of a changed region", []Hunk{{OldStart: 5, OldCount: 4, NewStart: 6, NewCount: 2}}, 5, 6, true},
+		{"inside a changed region", []Hunk{{OldStart: 5, OldCount: 4, NewStart: 6, NewCount: 3}}, 6, 7, true},
+		{"past the end of a shorter replacement", []Hunk{{OldStart: 5, OldCount: 4, NewStart: 6, NewCount: 2}}, 8, 7, true},
+		{"line deleted", []Hunk{{OldStart: 5, OldCount: 2, NewStart: 4, NewCount: 0}}, 6, 4, true},
+		{"first line deleted", []Hunk{{OldStart: 1, OldCount: 1, NewStart: 0, NewCount: 0}}, 1, 1, true},
+		{"line after a changed region", []Hunk{{OldStart: 5, OldCount: 4, NewStart: 5, NewCount: 1}}, 9, 6, false},
+	}
+	for _, tt := range tests {
+		got, changed := MapLine(tt.hunks, tt.line)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("%s: MapLine(%d) = %d, %v, want %d, %v", tt.name, tt.line, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in           string
+		start, count int
+	}{
+		{"12", 12, 1},
+		{"12,3", 12, 3},
+		{"12,0", 12, 0},
+		{"0,0", 0, 0},
+	}
+	for _, tt := range tests {
+		if start, count := parseRange(tt.in); start != tt.start || count != tt.count {
+			t.Errorf("parseRange(%q) = %d, %d, want %d, %d", tt.in, start, count, tt.start, tt.count)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Package highlight splits source code into coloured tokens. It knows just
+// enough about common languages — keywords, comments, strings and numbers —
+// to make excerpts of referenced files readable, and leaves colouring the
+// tokens to the caller: terminals style them, HTML marks them with classes.
+package highlight
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Kind classifies a token.
+type Kind int
+
+const (
+	Plain Kind = iota
+	Keyword
+	String
+	Comment
+	Number
+)
+
+// Token is a run of text of a single kind.
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// Lang describes the lexical syntax of a language.
+type Lang struct {
+	Name string
+	// LineComments start a comment running to the end of the line.
+	LineComments []string
+	// BlockComments are pairs of opening and closing delimiters.
+	BlockComments [][2]string
+	// Quotes delimit single-line strings.
+	Quotes []string
+	// RawQuotes delimit strings that may span lines.
+	RawQuotes []string
+	Keywords  map[string]bool
+}
+
+func keywords(words string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	goLang = &Lang{
+		Name:          "go",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{`"`, "'"},
+		RawQuotes:     []string{"`"},
+		Keywords: keywords(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false iota`),
+	}
+	cLike = &Lang{
+		Name:          "c",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{`"`, "'"},
+		Keywords: keywords(`auto break case catch char class const continue default delete do double else enum
+			extern false final float for friend goto if inline int long namespace new null nullptr override
+			private protected public return short signed sizeof static struct switch template this throw true
+			try typedef typename union unsigned using virtual void volatile while boolean byte extends
+			implements import instanceof interface package super synchronized throws var val fun when object`),
+	}
+	jsLang = &Lang{
+		Name:          "javascript",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{`"`, "'"},
+		RawQuotes:     []string{"`"},
+		Keywords: keywords(`async await break case catch class const continue debugger default delete do else
+			enum export extends false finally for from function if implements import in instanceof interface
+			let new null of private protected public return static super switch this throw true try type
+			typeof undefined var void while with yield`),
+	}
+	rustLang = &Lang{
+		Name:          "rust",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{`"`},
+		Keywords: keywords(`as async await break const continue crate dyn else enum extern false fn for if impl
+			in let loop match mod move mut pub ref return self Self static struct super trait true type
+			unsafe use where while`),
+	}
+	pythonLang = &Lang{
+		Name:         "python",
+		LineComments: []string{"#"},
+		Quotes:       []string{`"`, "'"},
+		RawQuotes:    []string{`"""`, "'''"},
+		Keywords: keywords(`and as assert async await break class continue def del elif else except False
+			finally for from global if import in is lambda None nonlocal not or pass raise return True try
+			while with yield self`),
+	}
+	rubyLang = &Lang{
+		Name:         "ruby",
+		LineComments: []string{"#"},
+		Quotes:       []string{`"`, "'"},
+		Keywords: keywords(`alias and begin break case class def do else elsif end ensure false for if
+			in module next nil not or redo rescue retry return self super then true undef unless until when
+			while yield require`),
+	}
+	shellLang = &Lang{
+		Name:         "shell",
+		LineComments: []string{"#"},
+		Quotes:       []string{`"`, "'"},
+		Keywords: keywords(`if then else elif fi case esac for while until do done in function return local
+			export exit echo set unset`),
+	}
+	sqlLang = &Lang{
+		Name:          "sql",
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{"'", `"`},
+		Keywords: keywords(`select from where and or not insert into values update set delete create table drop
+			alter index join left right inner outer on group by order having limit as null is in like
+			SELECT FROM WHERE AND OR NOT INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE DROP ALTER INDEX
+			JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT AS NULL IS IN LIKE`),
+	}
+	yamlLang = &Lang{
+		Name:         "yaml",
+		LineComments: []string{"#"},
+		Quotes:       []string{`"`, "'"},
+		Keywords:     keywords(`true false null yes no on off`),
+	}
+	jsonLang = &Lang{
+		Name:     "json",
+		Quotes:   []string{`"`},
+		Keywords: keywords(`true false null`),
+	}
+	htmlLang = &Lang{
+		Name:          "html",
+		BlockComments: [][2]string{{"<!--", "-->"}},
+		Quotes:        []string{`"`, "'"},
+		Keywords:      map[string]bool{},
+	}
+	cssLang = &Lang{
+		Name:          "css",
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []string{`"`, "'"},
+		Keywords:      keywords(`important inherit initial none auto`),
+	}
+	plainLang = &Lang{Name: "text", Keywords: map[string]bool{}}
+)
+
+var byExt = map[string]*Lang{
+	".go":    goLang,
+	".c":     cLike,
+	".h":     cLike,
+	".cc":    cLike,
+	".cpp":   cLike,
+	".hpp":   cLike,
+	".cs":    cLike,
+	".java":  cLike,
+	".kt":    cLike,
+	".kts":   cLike,
+	".swift": cLike,
+	".scala": cLike,
+	".js":    jsLang,
+	".jsx":   jsLang,
+	".mjs":   jsLang,
+	".cjs":   jsLang,
+	".ts":    jsLang,
+	".tsx":   jsLang,
+	".rs":    rustLang,
+	".py":    pythonLang,
+	".rb":    rubyLang,
+	".sh":    shellLang,
+	".bash":  shellLang,
+	".zsh":   shellLang,
+	".sql":   sqlLang,
+	".yml":   yamlLang,
+	".yaml":  yamlLang,
+	".toml":  yamlLang,
+	".json":  jsonLang,
+	".html":  htmlLang,
+	".htm":   htmlLang,
+	".xml":   htmlLang,
+	".vue":   htmlLang,
+	".css":   cssLang,
+	".scss":  cssLang,
+}
+
+var byName = map[string]*Lang{
+	"go": goLang, "golang": goLang,
+	"c": cLike, "cpp": cLike, "c++": cLike, "java": cLike, "csharp": cLike, "cs": cLike, "kotlin": cLike, "swift": cLike,
+	"js": jsLang, "javascript": jsLang, "ts": jsLang, "typescript": jsLang, "jsx": jsLang, "tsx": jsLang,
+	"rust": rustLang, "rs": rustLang,
+	"python": pythonLang, "py": pythonLang,
+	"ruby": rubyLang, "rb": rubyLang,
+	"sh": shellLang, "bash": shellLang, "shell": shellLang, "zsh": shellLang,
+	"sql": sqlLang, "yaml": yamlLang, "yml": yamlLang, "toml": yamlLang, "json": jsonLang,
+	"html": htmlLang, "xml": htmlLang, "css": cssLang,
+}
+
+// ForFile returns the language of a file based on its name. Unknown files
+// are plain text.
+func ForFile(path string) *Lang {
+	switch strings.ToLower(filepath.Base(path)) {
+	case "makefile", "dockerfile", ".bashrc", ".zshrc":
+		return shellLang
+	}
+	if l, ok := byExt[strings.ToLower(filepath.Ext(path))]; ok {
+		return l
+	}
+	return plainLang
+}
+
+// ForName returns the language for a name such as the info string of a
+// markdown code fence. Unknown names are plain text.
+func ForName(name string) *Lang {
+	if l, ok := byName[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return l
+	}
+	return plainLang
+}
+
+// Lines tokenizes consecutive lines of a file. Comments and raw strings may
+// span lines; excerpts that start inside one are highlighted as code.
+func Lines(lang *Lang, lines []string) [][]Token {
+	out := make([][]Token, len(lines))
+	closer := ""
+	closerKind := Plain
+	for i, line := range lines {
+		out[i], closer, closerKind = tokenizeLine(lang, line, closer, closerKind)
+	}
+	return out
+}
+
+// tokenizeLine tokenizes one line. closer is the delimiter that ends a
+// comment or string left open by the previous line.
+func tokenizeLine(lang *Lang, line, closer string, closerKind Kind) ([]Token, string, Kind) {
+	var toks []Token
+	emit := func(k Kind, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(toks); n > 0 && toks[n-1].Kind == k {
+			toks[n-1].Text += text
+			return
+		}
+		toks = append(toks, Token{k, text})
+	}
+
+	i := 0
+	if closer != "" {
+		end := strings.Index(line, closer)
+		if end < 0 {
+			emit(closerKind, line)
+			return toks, closer, closerKind
+		}
+		emit(closerKind, line[:end+len(closer)])
+		i = end + len(closer)
+	}
+
+	for i < len(line) {
+		rest := line[i:]
+
+		if p := prefixOf(rest, lang.LineComments); p != "" {
+			emit(Comment, rest)
+			break
+		}
+		if pair, ok := blockOf(rest, lang.BlockComments); ok {
+			end := strings.Index(rest[len(pair[0]):], pair[1])
+			if end < 0 {
+				emit(Comment, rest)
+				return toks, pair[1], Comment
+			}
+			n := len(pair[0]) + end + len(pair[1])
+			emit(Comment, rest[:n])
+			i += n
+			continue
+		}
+		if q := prefixOf(rest, lang.RawQuotes); q != "" {
+			end := strings.Index(rest[len(q):], q)
+			if end < 0 {
+				emit(String, rest)
+				return toks, q, String
+			}
+			n := len(q) + end + len(q)
+			emit(String, rest[:n])
+			i += n
+			continue
+		}
+		if q := prefixOf(rest, lang.Quotes); q != "" {
+			n := len(q)
+			for n < len(rest) {
+				if rest[n] == '\\' {
+					n += 2
+					continue
+				}
+				if strings.HasPrefix(rest[n:], q) {
+					n += len(q)
+					break
+				}
+				n++
+			}
+			n = min(n, len(rest))
+			emit(String, rest[:n])
+			i += n
+			continue
+		}
+
+		r := rune(rest[0])
+		switch {
+		case isIdentStart(r):
+			n := 1
+			for n < len(rest) && isIdentPart(rune(rest[n])) {
+				n++
+			}
+			word := rest[:n]
+			if lang.Keywords[word] {
+				emit(Keyword, word)
+			} else {
+				emit(Plain, word)
+			}
+			i += n
+		case unicode.IsDigit(r):
+			n := 1
+			for n < len(rest) && (isIdentPart(rune(rest[n])) || rest[n] == '.') {
+				n++
+			}
+			emit(Number, rest[:n])
+			i += n
+		default:
+			emit(Plain, rest[:1])
+			i++
+		}
+	}
+	return toks, "", Plain
+}
+
+func prefixOf(s string, prefixes []string) string {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func blockOf(s string, pairs [][2]string) ([2]string, bool) {
+	for _, p := range pairs {
+		if strings.HasPrefix(s, p[0]) {
+			return p, true
+		}
+	}
+	return [2]string{}, false
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || r >= 0x80
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+// String returns the lower-case name of the kind, e.g. "keyword".
+func (k Kind) String() string {
+	switch k {
+	case Keyword:
+		return "keyword"
+	case String:
+		return "string"
+	case Comment:
+		return "comment"
+	case Number:
+		return "number"
+	}
+	return "plain"
+}
+
+// HTML renders tokens as escaped HTML, with every token but plain text in a
+// span of class "hl-" and its kind, e.g. <span class="hl-keyword">.
+func HTML(toks []Token) string {
+	var b strings.Builder
+	for _, t := range toks {
+		text := html.EscapeString(t.Text)
+		if t.Kind == Plain {
+			b.WriteString(text)
+			continue
+		}
+		fmt.Fprintf(&b, `<span class="hl-%s">%s</span>`, t.Kind, text)
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	neturl "net/url"
+	"strings"
+
+	"spjoes/notes/highlight"
+)
+
+// HTML renders markdown as an HTML fragment for web pages. Text is escaped,
+// so raw HTML in a message shows as written, and only http, https and
+// mailto links are made clickable. Code blocks are highlighted with the
+// classes of highlight.HTML.
+func HTML(src string) string {
+	var b strings.Builder
+	// lists holds the tags of the open lists, outermost first; each has an
+	// open <li>
+	var lists []string
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			fmt.Fprintf(&b, "</li></%s>\n", lists[len(lists)-1])
+			lists = lists[:len(lists)-1]
+		}
+	}
+
+	for _, bl := range parse(src) {
+		if bl.kind != listItem {
+			closeLists(0)
+		}
+
+		switch bl.kind {
+		case heading:
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", bl.level, inlineHTML(bl.text), bl.level)
+
+		case paragraph:
+			fmt.Fprintf(&b, "<p>%s</p>\n", inlineHTML(bl.text))
+
+		case listItem:
+			tag, start := "ul", ""
+			if bl.marker[0] >= '0' && bl.marker[0] <= '9' {
+				tag = "ol"
+				if n := strings.TrimRight(bl.marker, ".)"); n != "1" {
+					start = fmt.Sprintf(` start="%s"`, n)
+				}
+			}
+			// an item can only be nested one level deeper than the last
+			depth := min(bl.level, len(lists))
+			closeLists(depth + 1)
+			if len(lists) == depth+1 && lists[depth] != tag {
+				closeLists(depth)
+			}
+			if len(lists) == depth+1 {
+				b.WriteString("</li>\n<li>")
+			} else {
+				fmt.Fprintf(&b, "<%s%s>\n<li>", tag, start)
+				lists = append(lists, tag)
+			}
+			b.WriteString(inlineHTML(bl.text))
+
+		case quote:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n", inlineHTML(bl.text))
+
+		case rule:
+			b.WriteString("<hr>\n")
+
+		case code:
+			lines := make([]string, len(bl.lines))
+			for i, toks := range highlight.Lines(highlight.ForName(bl.lang), bl.lines) {
+				lines[i] = highlight.HTML(toks)
+			}
+			fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", strings.Join(lines, "\n"))
+		}
+	}
+	closeLists(0)
+	return b.String()
+}
+
+// inlineHTML renders the inline markdown of a block as HTML.
+func inlineHTML(s string) string {
+	var b strings.Builder
+	spans := inline(s)
+	for i := 0; i < len(spans); i++ {
+		sp := spans[i]
+		text := html.EscapeString(sp.text)
+		switch sp.kind {
+		case bold:
+			fmt.Fprintf(&b, "<strong>%s</strong>", text)
+		case italic:
+			fmt.Fprintf(&b, "<em>%s</em>", text)
+		case codeSpan:
+			fmt.Fprintf(&b, "<code>%s</code>", text)
+		case lineBreak:
+			b.WriteString("<br>")
+		case link:
+			// a link's label is followed by its target
+			if i+1 < len(spans) && spans[i+1].kind == url && safeURL(spans[i+1].text) {
+				fmt.Fprintf(&b, `<a href="%s" target="_blank" rel="noopener">%s</a>`, html.EscapeString(spans[i+1].text), text)
+				i++
+			} else {
+				b.WriteString(text + " ")
+			}
+		case url:
+			if safeURL(sp.text) {
+				fmt.Fprintf(&b, `<a href="%s" target="_blank" rel="noopener">%s</a>`, text, text)
+			} else {
+				b.WriteString(text)
+			}
+		default:
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+// safeURL reports whether a link target may be followed from a web page.
+func safeURL(target string) bool {
+	u, err := neturl.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type spanKind int
+
+const (
+	text spanKind = iota
+	bold
+	italic
+	codeSpan
+	link
+	url
+	lineBreak
+)
+
+// span is a run of inline text of a single kind.
+type span struct {
+	kind spanKind
+	text string
+}
+
+// inline splits the text of a block into spans.
+func inline(s string) []span {
+	var spans []span
+	var plain strings.Builder
+	flush := func() {
+		if plain.Len() > 0 {
+			spans = append(spans, span{text, plain.String()})
+			plain.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!<>", rune(rest[1])):
+			plain.WriteByte(rest[1])
+			i += 2
+			continue
+
+		case rest[0] == '\n':
+			flush()
+			spans = append(spans, span{lineBreak, ""})
+			i++
+			continue
+
+		case rest[0] == '`':
+			ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if end := strings.Index(rest[ticks:], rest[:ticks]); end >= 0 {
+				flush()
+				spans = append(spans, span{codeSpan, strings.TrimSpace(rest[ticks : ticks+end])})
+				i += ticks + end + ticks
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				flush()
+				spans = append(spans, span{bold, rest[2 : 2+end]})
+				i += 2 + end + 2
+				continue
+			}
+
+		case rest[0] == '*' || rest[0] == '_':
+			// "_" only emphasises at word boundaries, so snake_case stays
+			boundary := rest[0] == '*' || i == 0 || !isWordByte(s[i-1])
+			if end := strings.IndexByte(rest[1:], rest[0]); boundary && end > 0 && rest[1] != ' ' {
+				after := 1 + end + 1
+				if rest[0] == '*' || after >= len(rest) || !isWordByte(rest[after]) {
+					flush()
+					spans = append(spans, span{italic, rest[1 : 1+end]})
+					i += after
+					continue
+				}
+			}
+
+		case rest[0] == '[':
+			if close := strings.Index(rest, "]("); close > 0 {
+				if end := strings.IndexByte(rest[close+2:], ')'); end >= 0 {
+					flush()
+					label, target := rest[1:close], rest[close+2:close+2+end]
+					if label != target {
+						spans = append(spans, span{link, label})
+					}
+					spans = append(spans, span{url, target})
+					i += close + 2 + end + 1
+					continue
+				}
+			}
+
+		case rest[0] == '<':
+			if end := strings.IndexByte(rest, '>'); end > 0 && strings.Contains(rest[1:end], "://") && !strings.Contains(rest[1:end], " ") {
+				flush()
+				spans = append(spans, span{url, rest[1:end]})
+				i += end + 1
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		plain.WriteString(rest[:size])
+		i += size
+	}
+	flush()
+	return spans
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// style renders the text of a span with the theme.
+func (sp span) style(s string, theme Theme) string {
+	switch sp.kind {
+	case bold:
+		return apply(theme.Bold, s)
+	case italic:
+		return apply(theme.Italic, s)
+	case codeSpan:
+		return apply(theme.Code, s)
+	case link:
+		return apply(theme.Link, s)
+	}
+	return s
+}
+
+// renderInline renders text without wrapping it.
+func renderInline(s string, theme Theme) string {
+	lines := wrap(inline(s), 0, "", "", theme)
+	return strings.Join(lines, " ")
+}
+
+// wrap lays spans out in lines at most width columns wide, counting the
+// prefixes; first starts the first line and rest every other line. Words
+// longer than a line are not broken.
+func wrap(spans []span, width int, first, rest string, theme Theme) []string {
+	var lines []string
+	var line strings.Builder
+	prefix := first
+	col := visibleLen(prefix)
+	start := col
+	line.WriteString(prefix)
+	pendingSpace := false
+
+	newLine := func() {
+		lines = append(lines, line.String())
+		line.Reset()
+		line.WriteString(rest)
+		col = visibleLen(rest)
+		start = col
+		pendingSpace = false
+	}
+
+	for _, sp := range spans {
+		if sp.kind == lineBreak {
+			newLine()
+			continue
+		}
+		txt := sp.text
+		if sp.kind == url {
+			txt = "(" + txt + ")"
+			if len(lines) > 0 || col > start {
+				pendingSpace = true
+			}
+		}
+
+		words := strings.Fields(txt)
+		if sp.kind == codeSpan {
+			words = []string{txt}
+		}
+		if len(words) == 0 {
+			if txt != "" {
+				pendingSpace = true
+			}
+			continue
+		}
+		if strings.HasPrefix(txt, " ") {
+			pendingSpace = true
+		}
+
+		for wi, w := range words {
+			if wi > 0 {
+				pendingSpace = true
+			}
+			n := utf8.RuneCountInString(w)
+			space := 0
+			if pendingSpace && col > start {
+				space = 1
+			}
+			if width > 0 && col > start && col+space+n > width {
+				newLine()
+				space = 0
+			}
+			if space > 0 {
+				line.WriteByte(' ')
+				col++
+			}
+			styled := sp.style(w, theme)
+			if sp.kind == url {
+				styled = apply(theme.URL, w)
+			}
+			line.WriteString(styled)
+			col += n
+			pendingSpace = false
+		}
+		if strings.HasSuffix(txt, " ") {
+			pendingSpace = true
+		}
+	}
+	lines = append(lines, line.String())
+	return lines
+}
+
+// visibleLen returns the width of a prefix, skipping ANSI escape sequences.
+func visibleLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b {
+			end := strings.IndexByte(s[i:], 'm')
+			if end < 0 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		n++
+		i += size
+	}
+	return n
+}
@@ -0,0 +1,221 @@
+// Package markdown renders the markdown of note messages for a terminal:
+// headings, paragraphs wrapped to a width, nested lists, block quotes, rules,
+// code fences with syntax highlighting, and inline emphasis, code and links.
+//
+// It covers the markdown people write in notes rather than all of
+// CommonMark. Like the highlight package it leaves colours to the caller,
+// through a Theme. HTML renders the same markdown for web pages.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"spjoes/notes/highlight"
+)
+
+// Theme styles the parts of a rendered document. A nil function leaves its
+// text unstyled.
+type Theme struct {
+	Heading func(level int, text string) string
+	Bold    func(string) string
+	Italic  func(string) string
+	Code    func(string) string
+	Link    func(string) string
+	URL     func(string) string
+	Quote   func(string) string
+	Marker  func(string) string
+	Rule    func(string) string
+	// Token styles the tokens of code blocks.
+	Token func(kind highlight.Kind, text string) string
+}
+
+// Plain is a theme without styles.
+var Plain = Theme{}
+
+func apply(f func(string) string, s string) string {
+	if f == nil || s == "" {
+		return s
+	}
+	return f(s)
+}
+
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	heading
+	listItem
+	quote
+	code
+	rule
+)
+
+type block struct {
+	kind  blockKind
+	text  string
+	level int // heading level, or list nesting depth
+	// marker is the bullet or number of a list item.
+	marker string
+	// lang is the info string of a code fence.
+	lang  string
+	lines []string
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listRe    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	fenceRe   = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+)
+
+// parse splits a document into blocks.
+func parse(src string) []block {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var blocks []block
+	var cur *block // the paragraph, quote or list item being continued
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			b := block{kind: code, lang: m[2]}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				b.lines = append(b.lines, lines[i])
+			}
+			blocks = append(blocks, b)
+			cur = nil
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			cur = nil
+			continue
+		case isRule(trimmed):
+			blocks = append(blocks, block{kind: rule})
+			cur = nil
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(trimmed); m != nil {
+			blocks = append(blocks, block{kind: heading, level: len(m[1]), text: m[2]})
+			cur = nil
+			continue
+		}
+		if m := listRe.FindStringSubmatch(line); m != nil {
+			depth := len(strings.ReplaceAll(m[1], "\t", "    ")) / 2
+			blocks = append(blocks, block{kind: listItem, level: depth, marker: m[2], text: m[3]})
+			cur = &blocks[len(blocks)-1]
+			continue
+		}
+		if rest, ok := strings.CutPrefix(trimmed, ">"); ok {
+			rest = strings.TrimSpace(rest)
+			if cur != nil && cur.kind == quote {
+				cur.text = joinText(cur.text, rest)
+			} else {
+				blocks = append(blocks, block{kind: quote, text: rest})
+				cur = &blocks[len(blocks)-1]
+			}
+			continue
+		}
+
+		// lazy continuation of the paragraph, quote or list item; trailing
+		// spaces are kept for hard line breaks
+		content := strings.TrimLeft(line, " \t")
+		if cur != nil {
+			cur.text = joinText(cur.text, content)
+			continue
+		}
+		blocks = append(blocks, block{kind: paragraph, text: content})
+		cur = &blocks[len(blocks)-1]
+	}
+	return blocks
+}
+
+// isRule reports whether a line is a thematic break: three or more of the
+// same "-", "*" or "_", optionally separated by spaces.
+func isRule(line string) bool {
+	s := strings.ReplaceAll(line, " ", "")
+	if len(s) < 3 || !strings.ContainsRune("-*_", rune(s[0])) {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+// joinText joins wrapped lines of a paragraph. A line ending in two spaces
+// or a backslash is a hard line break.
+func joinText(text, line string) string {
+	if text == "" {
+		return line
+	}
+	if strings.HasSuffix(text, "  ") || strings.HasSuffix(text, `\`) {
+		return strings.TrimRight(strings.TrimSuffix(text, `\`), " ") + "\n" + line
+	}
+	return text + " " + line
+}
+
+// Render renders markdown for a terminal width columns wide. A width of
+// zero or less leaves paragraphs unwrapped.
+func Render(src string, width int, theme Theme) string {
+	var out []string
+	blocks := parse(src)
+	for i, b := range blocks {
+		// lists are kept together; every other block is set apart
+		if i > 0 && !(b.kind == listItem && blocks[i-1].kind == listItem) {
+			out = append(out, "")
+		}
+
+		switch b.kind {
+		case heading:
+			text := renderInline(b.text, theme)
+			if theme.Heading != nil {
+				text = theme.Heading(b.level, text)
+			}
+			out = append(out, text)
+
+		case paragraph:
+			out = append(out, wrap(inline(b.text), width, "", "", theme)...)
+
+		case listItem:
+			indent := strings.Repeat("  ", b.level)
+			bullet := "• "
+			if b.marker[0] >= '0' && b.marker[0] <= '9' {
+				bullet = b.marker + " "
+			}
+			first := indent + apply(theme.Marker, bullet)
+			rest := indent + strings.Repeat(" ", len([]rune(bullet)))
+			out = append(out, wrap(inline(b.text), width, first, rest, theme)...)
+
+		case quote:
+			bar := apply(theme.Quote, "│ ")
+			lines := wrap(inline(b.text), width, bar, bar, theme)
+			out = append(out, lines...)
+
+		case rule:
+			n := 40
+			if width > 0 {
+				n = min(n, width)
+			}
+			out = append(out, apply(theme.Rule, strings.Repeat("─", n)))
+
+		case code:
+			toks := highlight.Lines(highlight.ForName(b.lang), b.lines)
+			for _, line := range toks {
+				var sb strings.Builder
+				sb.WriteString("  ")
+				for _, t := range line {
+					text := strings.ReplaceAll(t.Text, "\t", "    ")
+					if theme.Token != nil {
+						text = theme.Token(t.Kind, text)
+					}
+					sb.WriteString(text)
+				}
+				out = append(out, sb.String())
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,247 @@
+// Package query parses the filter language shared by notes list, notes
+// delete and the TUI search bar.
+//
+// A query is a sequence of terms combined with AND, OR and NOT (upper case)
+// and grouped with parentheses. Adjacent terms are ANDed. A term is either
+// free text, matched case-insensitively against the message and file of a
+// note, a "quoted phrase", or a field filter:
+//
+//	tag:bug            notes tagged bug (#bug is shorthand)
+//	file:src/**/*.go   notes on files matching a glob
+//	line:>100          notes by line; also <, >=, <=, = and 10..20
+//	created:>2025-01-01
+//	author:alice       substring of the author's name or email
+//	branch:main        notes written on a branch
+//	message:"to do"    substring of the message only
+//	id:1a2b3c4d        notes whose ID starts with the value
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"spjoes/notes/store"
+)
+
+// Query is a parsed query. The zero Query matches every note.
+type Query struct {
+	expr node
+}
+
+// Parse parses a query. An empty or blank query matches every note.
+func Parse(s string) (*Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if len(toks) == 0 {
+		return &Query{}, nil
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return &Query{expr: expr}, nil
+}
+
+// Match reports whether the note satisfies the query.
+func (q *Query) Match(n store.Note) bool {
+	if q == nil || q.expr == nil {
+		return true
+	}
+	return q.expr.match(n)
+}
+
+// Error is a syntax error in a query.
+type Error struct {
+	// Pos is the byte offset of the error in the query.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query at column %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written; value has quotes removed.
+	text  string
+	value string
+	// quoted is set when any part of the token was quoted, so it is never
+	// taken for an operator.
+	quoted bool
+	pos    int
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		default:
+			start := i
+			var value strings.Builder
+			quoted := false
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' {
+					break
+				}
+				if r != '"' {
+					value.WriteString(s[i : i+size])
+					i += size
+					continue
+				}
+				quoted = true
+				i++
+				closed := false
+				for i < len(s) {
+					r, size := utf8.DecodeRuneInString(s[i:])
+					if r == '\\' && i+1 < len(s) {
+						_, next := utf8.DecodeRuneInString(s[i+1:])
+						value.WriteString(s[i+1 : i+1+next])
+						i += 1 + next
+						continue
+					}
+					if r == '"' {
+						closed = true
+						i++
+						break
+					}
+					value.WriteString(s[i : i+size])
+					i += size
+				}
+				if !closed {
+					return nil, &Error{Pos: start, Msg: "unterminated quote"}
+				}
+			}
+			toks = append(toks, token{kind: tokWord, text: s[start:i], value: value.String(), quoted: quoted, pos: start})
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.i >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.i], true
+}
+
+func (p *parser) isOperator(op string) bool {
+	t, ok := p.peek()
+	return ok && t.kind == tokWord && !t.quoted && t.text == op
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("OR") {
+		p.i++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: not (["AND"] not)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokRParen || p.isOperator("OR") {
+			return left, nil
+		}
+		if p.isOperator("AND") {
+			p.i++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+// parseNot parses: "NOT" not | primary
+func (p *parser) parseNot() (node, error) {
+	if p.isOperator("NOT") {
+		p.i++
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: "(" or ")" | term
+func (p *parser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		end := 0
+		if n := len(p.toks); n > 0 {
+			last := p.toks[n-1]
+			end = last.pos + len(last.text)
+		}
+		return nil, &Error{Pos: end, Msg: "expected a term"}
+	}
+	switch {
+	case t.kind == tokLParen:
+		p.i++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokRParen {
+			return nil, &Error{Pos: t.pos, Msg: "unclosed parenthesis"}
+		}
+		p.i++
+		return inner, nil
+	case t.kind == tokRParen:
+		return nil, &Error{Pos: t.pos, Msg: `unexpected ")"`}
+	case !t.quoted && (t.text == "AND" || t.text == "OR"):
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("expected a term before %s", t.text)}
+	}
+	p.i++
+	return parseTerm(t)
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"spjoes/notes/store"
+)
+
+var testNotes = map[string]store.Note{
+	"bug": {
+		ID:        "1a2b3c4d-0000-0000-0000-000000000000",
+		Message:   "Fix the retry loop",
+		File:      "src/net/client.go",
+		Line:      42,
+		EndLine:   50,
+		Tags:      []string{"bug", "Urgent"},
+		Author:    "Alice Smith <alice@example.com>",
+		Branch:    "main",
+		CreatedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	},
+	"doc": {
+		ID:        "9f8e7d6c-0000-0000-0000-000000000000",
+		Message:   "voilà, Åsa said so",
+		File:      "docs/README.md",
+		Tags:      []string{"docs"},
+		Author:    "Åsa Berg <asa@example.com>",
+		Branch:    "feature/docs",
+		CreatedAt: time.Date(2024, 12, 24, 8, 0, 0, 0, time.UTC),
+	},
+	"plain": {
+		ID:        "5e5e5e5e-0000-0000-0000-000000000000",
+		Message:   "to do: tidy up",
+		CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestParseMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"bug", "doc", "plain"}},
+		{"   ", []string{"bug", "doc", "plain"}},
+		{"retry", []string{"bug"}},
+		{"RETRY", []string{"bug"}},
+		{"client.go", []string{"bug"}},
+		{`"to do"`, []string{"plain"}},
+		{"to do", []string{"plain"}},
+
+		// non-ASCII words are not split at UTF-8 continuation bytes
+		{"voilà", []string{"doc"}},
+		{"Åsa", []string{"doc"}},
+		{"åsa said", []string{"doc"}},
+		{`"voilà, Åsa"`, []string{"doc"}},
+		{"author:Åsa", []string{"doc"}},
+		{"message:voilà ", []string{"doc"}},
+
+		{"tag:bug", []string{"bug"}},
+		{"#urgent", []string{"bug"}},
+		{"tag:do*", []string{"doc"}},
+		{"file:src/**/*.go", []string{"bug"}},
+		{"file:*.md", []string{"doc"}},
+		{"line:45", []string{"bug"}},
+		{"line:51", nil},
+		{"line:>40", []string{"bug"}},
+		{"line:1..41", nil},
+		{"created:>2025-01-01", []string{"bug", "plain"}},
+		{"author:alice", []string{"bug"}},
+		{"branch:feature/docs", []string{"doc"}},
+		{"id:1A2B", []string{"bug"}},
+
+		{"tag:bug OR tag:docs", []string{"bug", "doc"}},
+		{"tag:bug AND tag:docs", nil},
+		{"NOT tag:bug", []string{"doc", "plain"}},
+		{"NOT (tag:bug OR tag:docs)", []string{"plain"}},
+		{"tidy OR (retry NOT file:*.md)", []string{"bug", "plain"}},
+		{`"OR"`, nil},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, name := range []string{"bug", "doc", "plain"} {
+			if q.Match(testNotes[name]) {
+				got = append(got, name)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) matches %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{`"unterminated`, 0},
+		{"(tag:bug", 0},
+		{"tag:bug)", 7},
+		{"AND tag:bug", 0},
+		{"tag:bug OR", 10},
+		{"NOT", 3},
+		{"tag:", 0},
+		{"line:abc", 0},
+		{"line:9..3", 0},
+		{"créé line:x", 7},
+		{"created:yesterday", 0},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		qerr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Parse(%q) = %v, want a query error", tt.query, err)
+			continue
+		}
+		if qerr.Pos != tt.pos {
+			t.Errorf("Parse(%q) fails at %d, want %d (%v)", tt.query, qerr.Pos, tt.pos, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package query
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"spjoes/notes/files"
+	"spjoes/notes/store"
+)
+
+type node interface {
+	match(n store.Note) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ inner node }
+
+func (a andNode) match(n store.Note) bool { return a.left.match(n) && a.right.match(n) }
+func (o orNode) match(n store.Note) bool  { return o.left.match(n) || o.right.match(n) }
+func (x notNode) match(n store.Note) bool { return !x.inner.match(n) }
+
+// matchFunc adapts a function to a node.
+type matchFunc func(n store.Note) bool
+
+func (f matchFunc) match(n store.Note) bool { return f(n) }
+
+// parseTerm turns a single word into a node.
+func parseTerm(t token) (node, error) {
+	// a token starting with a quote is a phrase, even if it contains a colon
+	if !strings.HasPrefix(t.text, `"`) {
+		if tag, ok := strings.CutPrefix(t.value, "#"); ok && tag != "" {
+			return tagTerm(tag), nil
+		}
+		if field, value, ok := strings.Cut(t.value, ":"); ok {
+			field = strings.ToLower(field)
+			if build, known := fields[field]; known {
+				if value == "" {
+					return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("%s: needs a value", field)}
+				}
+				nd, err := build(value)
+				if err != nil {
+					return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("%s: %v", field, err)}
+				}
+				return nd, nil
+			}
+		}
+	}
+
+	// free text, or a phrase matched as a whole
+	needle := strings.ToLower(t.value)
+	return matchFunc(func(n store.Note) bool {
+		return strings.Contains(strings.ToLower(n.Message), needle) ||
+			strings.Contains(strings.ToLower(n.File), needle)
+	}), nil
+}
+
+var fields = map[string]func(value string) (node, error){
+	"tag":     func(v string) (node, error) { return tagTerm(v), nil },
+	"file":    fileTerm,
+	"line":    lineTerm,
+	"created": createdTerm,
+	"author": func(v string) (node, error) {
+		return containsTerm(v, func(n store.Note) string { return n.Author }), nil
+	},
+	"branch": func(v string) (node, error) {
+		return matchFunc(func(n store.Note) bool { return n.Branch == v }), nil
+	},
+	"message": func(v string) (node, error) {
+		return containsTerm(v, func(n store.Note) string { return n.Message }), nil
+	},
+	"id": func(v string) (node, error) {
+		v = strings.ToLower(v)
+		return matchFunc(func(n store.Note) bool { return strings.HasPrefix(n.ID, v) }), nil
+	},
+}
+
+// tagTerm matches notes with a tag, ignoring case. The tag may be a glob.
+func tagTerm(tag string) node {
+	tag = strings.ToLower(tag)
+	return matchFunc(func(n store.Note) bool {
+		for _, t := range n.Tags {
+			t = strings.ToLower(t)
+			if t == tag {
+				return true
+			}
+			if ok, _ := path.Match(tag, t); ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func containsTerm(v string, field func(store.Note) string) node {
+	v = strings.ToLower(v)
+	return matchFunc(func(n store.Note) bool {
+		return strings.Contains(strings.ToLower(field(n)), v)
+	})
+}
+
+// fileTerm matches notes on files matching a glob. "**" matches any number
+// of directories, and a pattern without a slash is matched against the base
+// name of the file as well as the whole path.
+func fileTerm(pattern string) (node, error) {
+	pattern = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(pattern, `\`, "/")), "/")
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	baseOnly := !strings.Contains(pattern, "/")
+	return matchFunc(func(n store.Note) bool {
+		if n.File == "" {
+			return false
+		}
+		file := strings.ReplaceAll(n.File, `\`, "/")
+		if files.Glob(pattern, file) {
+			return true
+		}
+		return baseOnly && files.Glob(pattern, path.Base(file))
+	}), nil
+}
+
+// comparison splits a leading >, >=, <, <= or = from value.
+func comparison(value string) (op, rest string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, op); ok {
+			return op, rest
+		}
+	}
+	return "=", value
+}
+
+func compare(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+// lineTerm matches notes by line. "line:42" matches notes covering line 42,
+// "line:10..20" notes starting in that range, and comparisons compare the
+// first line of the note. Notes without a line never match.
+func lineTerm(value string) (node, error) {
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || lo > hi {
+			return nil, fmt.Errorf("invalid range %q", value)
+		}
+		return matchFunc(func(n store.Note) bool { return n.Line >= lo && n.Line <= hi }), nil
+	}
+
+	op, rest := comparison(value)
+	line, err := strconv.Atoi(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line %q", rest)
+	}
+	if op == "=" {
+		return matchFunc(func(n store.Note) bool { return n.Covers(line) }), nil
+	}
+	return matchFunc(func(n store.Note) bool {
+		return n.Line > 0 && compare(op, n.Line-line)
+	}), nil
+}
+
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// createdTerm compares the creation time of notes. A date without a time
+// covers the whole day in local time, so "created:2025-01-01" matches every
+// note from that day and "created:>2025-01-01" those from the day after.
+func createdTerm(value string) (node, error) {
+	op, rest := comparison(value)
+	var start time.Time
+	var err error
+	for _, layout := range dateLayouts {
+		if start, err = time.ParseInLocation(layout, rest, time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", rest)
+	}
+	end := start
+	if len(rest) == len("2006-01-02") {
+		end = start.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return matchFunc(func(n store.Note) bool {
+		t := n.CreatedAt
+		switch op {
+		case ">":
+			return t.After(end)
+		case ">=":
+			return !t.Before(start)
+		case "<":
+			return t.Before(start)
+		case "<=":
+			return !t.After(end)
+		}
+		return !t.Before(start) && !t.After(end)
+	}), nil
+}
@@ -0,0 +1,105 @@
+// Package scan finds TODO, FIXME, HACK and NOTE markers in the comments of
+// source files. Comments are found with the tokenizer of the highlight
+// package, so markers inside strings, or in files without comment syntax,
+// are left alone.
+package scan
+
+import (
+	"regexp"
+	"strings"
+
+	"spjoes/notes/highlight"
+)
+
+// Markers are the markers found by default.
+var Markers = []string{"TODO", "FIXME", "HACK", "NOTE"}
+
+// Item is a marker found in a file.
+type Item struct {
+	File string
+	Line int
+	// Marker is the marker as written in the code, e.g. "TODO".
+	Marker string
+	// Text is the rest of the comment, e.g. "handle errors" for
+	// "// TODO(ann): handle errors".
+	Text string
+}
+
+// Tag returns the tag of notes made for the marker.
+func (it Item) Tag() string {
+	return strings.ToLower(it.Marker)
+}
+
+// Message returns the message of the note made for the marker.
+func (it Item) Message() string {
+	if it.Text == "" {
+		return it.Marker
+	}
+	return it.Text
+}
+
+// Scanner finds markers in files.
+type Scanner struct {
+	re *regexp.Regexp
+}
+
+// New returns a Scanner for the markers, which are matched as whole,
+// case-sensitive words at the start of a comment.
+func New(markers []string) *Scanner {
+	quoted := make([]string, len(markers))
+	for i, m := range markers {
+		quoted[i] = regexp.QuoteMeta(m)
+	}
+	// the marker may be followed by an owner or issue in parentheses and
+	// a colon, as in "TODO(ann): " or "FIXME: "
+	re := regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)\b(?:\([^)]*\))?:?\s*(.*)$`)
+	return &Scanner{re: re}
+}
+
+// Supported reports whether markers can be found in a file, that is whether
+// its language has comments.
+func Supported(file string) bool {
+	lang := highlight.ForFile(file)
+	return len(lang.LineComments) > 0 || len(lang.BlockComments) > 0
+}
+
+// File returns the markers in the lines of file, with 1-based line numbers.
+func (s *Scanner) File(file string, lines []string) []Item {
+	lang := highlight.ForFile(file)
+	var items []Item
+	for i, toks := range highlight.Lines(lang, lines) {
+		for _, t := range toks {
+			if t.Kind != highlight.Comment {
+				continue
+			}
+			m := s.re.FindStringSubmatch(commentText(lang, t.Text))
+			if m == nil {
+				continue
+			}
+			items = append(items, Item{File: file, Line: i + 1, Marker: m[1], Text: strings.TrimSpace(m[2])})
+			// one marker per line
+			break
+		}
+	}
+	return items
+}
+
+// commentText strips the delimiters of a comment, and the decoration at the
+// start of the lines of a block comment, leaving its text.
+func commentText(lang *highlight.Lang, text string) string {
+	text = strings.TrimSpace(text)
+	for _, d := range lang.LineComments {
+		if rest, ok := strings.CutPrefix(text, d); ok {
+			// "///" and "##" are comments too
+			return strings.TrimSpace(strings.TrimLeft(rest, d[:1]))
+		}
+	}
+	for _, pair := range lang.BlockComments {
+		text = strings.TrimPrefix(text, pair[0])
+		text = strings.TrimSuffix(text, pair[1])
+	}
+	text = strings.TrimSpace(text)
+	// " * TODO" inside a block comment
+	text = strings.TrimLeft(text, "*!- \t")
+	return strings.TrimSpace(text)
+}
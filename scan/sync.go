@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"slices"
+
+	"spjoes/notes/store"
+)
+
+// Tag is carried by every note created by a scan, so a later scan can tell
+// them from notes written by hand.
+const Tag = "scanned"
+
+// ResolvedTag marks scanned notes whose comment is no longer in the code.
+const ResolvedTag = "resolved"
+
+// Match pairs a scanned note with the marker it was made for.
+type Match struct {
+	Note store.Note
+	Item Item
+}
+
+// Changes is what a scan changes in the notes.
+type Changes struct {
+	// Added are markers without a note.
+	Added []Item
+	// Updated are notes whose marker moved, or came back after being
+	// resolved.
+	Updated []Match
+	// Resolved are notes whose marker is gone.
+	Resolved []store.Note
+	// Unchanged counts the notes whose marker is where it was.
+	Unchanged int
+}
+
+// Reconcile compares the notes of earlier scans with the markers found now.
+// A marker belongs to a note with its tag and message in the same file,
+// closest lines first; a marker whose file was renamed is matched in any
+// file.
+func Reconcile(notes []store.Note, items []Item) Changes {
+	var scanned []store.Note
+	for _, n := range notes {
+		if n.HasTag(Tag) {
+			scanned = append(scanned, n)
+		}
+	}
+
+	var c Changes
+	pairedNote := make([]bool, len(scanned))
+	pairedItem := make([]bool, len(items))
+	pair := func(key func(message, file string) string) {
+		byKey := map[string][]int{}
+		for i, n := range scanned {
+			if !pairedNote[i] {
+				k := key(n.Message, n.File)
+				byKey[k] = append(byKey[k], i)
+			}
+		}
+		for _, candidates := range byKey {
+			slices.SortStableFunc(candidates, func(a, b int) int { return scanned[a].Line - scanned[b].Line })
+		}
+		for j, it := range items {
+			if pairedItem[j] {
+				continue
+			}
+			// items come in line order, so the first note left is the
+			// closest
+			k := key(it.Message(), it.File)
+			at := slices.IndexFunc(byKey[k], func(i int) bool { return scanned[i].HasTag(it.Tag()) })
+			if at < 0 {
+				continue
+			}
+			i := byKey[k][at]
+			byKey[k] = slices.Delete(byKey[k], at, at+1)
+			pairedNote[i], pairedItem[j] = true, true
+
+			n := scanned[i]
+			if n.File == it.File && n.Line == it.Line && !n.HasTag(ResolvedTag) {
+				c.Unchanged++
+			} else {
+				c.Updated = append(c.Updated, Match{Note: n, Item: it})
+			}
+		}
+	}
+	pair(func(message, file string) string { return file + "\x00" + message })
+	pair(func(message, _ string) string { return message })
+
+	for j, it := range items {
+		if !pairedItem[j] {
+			c.Added = append(c.Added, it)
+		}
+	}
+	for i, n := range scanned {
+		if !pairedNote[i] && !n.HasTag(ResolvedTag) {
+			c.Resolved = append(c.Resolved, n)
+		}
+	}
+	return c
+}
@@ -0,0 +1,313 @@
+// Package search maintains a full-text index of notes. The index maps the
+// stemmed words of each note's message, tags and file name to the notes that
+// contain them and ranks matches with BM25.
+//
+// The index is a cache local to each checkout: it records the
+// store.Fingerprint of the notes it was synced with and a hash of every note
+// it has indexed. When the fingerprint no longer matches, Sync brings it up
+// to date by re-indexing only the notes that changed, so writes that bypass
+// IndexedStore (another process, a git pull, notes doctor) are picked up on
+// the next search.
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"spjoes/notes/store"
+)
+
+// Version is the format of the persisted index. Indexes written in another
+// format are rebuilt.
+const Version = 1
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Tags count for more than words of the message.
+const tagWeight = 3
+
+// Index is an inverted index of notes.
+type Index struct {
+	Version int `json:"version"`
+	// Docs holds what was indexed for each note, by full ID.
+	Docs map[string]Doc `json:"docs"`
+	// Postings maps each stemmed term to the notes containing it and how
+	// often it occurs in them.
+	Postings map[string]map[string]int `json:"postings"`
+	// TotalLen is the sum of the lengths of every document.
+	TotalLen int `json:"total_len"`
+	// Fingerprint is the store.Fingerprint of the notes the index was last
+	// synced with.
+	Fingerprint uint64 `json:"fingerprint,omitempty"`
+}
+
+// Doc is an indexed note.
+type Doc struct {
+	Hash  string   `json:"hash"`
+	Len   int      `json:"len"`
+	Terms []string `json:"terms"`
+}
+
+// Hit is a note matching a search, with its relevance.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{Version: Version, Docs: map[string]Doc{}, Postings: map[string]map[string]int{}}
+}
+
+// Build indexes notes from scratch.
+func Build(notes []store.Note) *Index {
+	idx := New()
+	for _, n := range notes {
+		idx.Put(n)
+	}
+	return idx
+}
+
+// Dir returns the directory the index of root is kept in.
+func Dir(root string) string {
+	return filepath.Join(root, ".notes", "index")
+}
+
+// Path returns the location of the persisted index of root.
+func Path(root string) string {
+	return filepath.Join(Dir(root), "index.json")
+}
+
+// Enabled reports whether root keeps a search index. The index is turned on
+// by creating its directory, so it stays local to the checkout.
+func Enabled(root string) bool {
+	info, err := os.Stat(Dir(root))
+	return err == nil && info.IsDir()
+}
+
+// Load reads the persisted index of root. A missing, unreadable or outdated
+// index yields an empty one, which Sync then fills.
+func Load(root string) *Index {
+	data, err := os.ReadFile(Path(root))
+	if err != nil {
+		return New()
+	}
+	idx := New()
+	if err := json.Unmarshal(data, idx); err != nil || idx.Version != Version || idx.Docs == nil || idx.Postings == nil {
+		return New()
+	}
+	return idx
+}
+
+// Save writes the index under root.
+func (idx *Index) Save(root string) error {
+	dir := Dir(root)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// the index is derived from the notes; keep it out of git
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return store.WriteFileAtomic(Path(root), data, 0644)
+}
+
+// Remove deletes the persisted index of root, disabling it.
+func Remove(root string) error {
+	err := os.RemoveAll(Dir(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Put indexes a note, replacing what was indexed for it before. It reports
+// whether the index changed.
+func (idx *Index) Put(n store.Note) bool {
+	hash := noteHash(n)
+	if old, ok := idx.Docs[n.ID]; ok {
+		if old.Hash == hash {
+			return false
+		}
+		idx.Delete(n.ID)
+	}
+
+	freq := documentTerms(n)
+	doc := Doc{Hash: hash, Terms: make([]string, 0, len(freq))}
+	for term, tf := range freq {
+		doc.Len += tf
+		doc.Terms = append(doc.Terms, term)
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = map[string]int{}
+		}
+		idx.Postings[term][n.ID] = tf
+	}
+	sort.Strings(doc.Terms)
+	idx.Docs[n.ID] = doc
+	idx.TotalLen += doc.Len
+	return true
+}
+
+// Delete removes a note from the index. It reports whether the note was
+// indexed.
+func (idx *Index) Delete(id string) bool {
+	doc, ok := idx.Docs[id]
+	if !ok {
+		return false
+	}
+	for _, term := range doc.Terms {
+		delete(idx.Postings[term], id)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	idx.TotalLen -= doc.Len
+	delete(idx.Docs, id)
+	return true
+}
+
+// Sync re-indexes the notes that changed since they were indexed and drops
+// notes that no longer exist. It reports whether the index changed.
+func (idx *Index) Sync(notes []store.Note) bool {
+	changed := false
+	seen := make(map[string]bool, len(notes))
+	for _, n := range notes {
+		seen[n.ID] = true
+		if idx.Put(n) {
+			changed = true
+		}
+	}
+	for id := range idx.Docs {
+		if !seen[id] && idx.Delete(id) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Search returns the notes matching any of the terms in text, most relevant
+// first. Notes matching more of the terms always rank above notes matching
+// fewer.
+func (idx *Index) Search(text string) []Hit {
+	terms := QueryTerms(text)
+	if len(terms) == 0 || len(idx.Docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.Docs))
+	avgLen := float64(idx.TotalLen) / n
+	scores := map[string]float64{}
+	matched := map[string]int{}
+	for _, term := range terms {
+		postings := idx.Postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			f := float64(tf)
+			norm := f * (k1 + 1) / (f + k1*(1-b+b*float64(idx.Docs[id].Len)/avgLen))
+			scores[id] += idf * norm
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if mi, mj := matched[hits[i].ID], matched[hits[j].ID]; mi != mj {
+			return mi > mj
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// documentTerms returns the stemmed terms of a note and how often each
+// occurs.
+func documentTerms(n store.Note) map[string]int {
+	freq := map[string]int{}
+	for _, w := range Words(n.Message) {
+		freq[Stem(w)]++
+	}
+	for _, tag := range n.Tags {
+		for _, w := range Words(tag) {
+			freq[Stem(w)] += tagWeight
+		}
+	}
+	if n.File != "" {
+		for _, w := range Words(filepath.Base(n.File)) {
+			freq[Stem(w)]++
+		}
+	}
+	return freq
+}
+
+// QueryTerms returns the distinct stemmed terms of a search.
+func QueryTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, w := range Words(text) {
+		t := Stem(w)
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// Words splits text into lower-case words, dropping common English words
+// that carry no meaning on their own.
+func Words(text string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		if !stopWords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+func noteHash(n store.Note) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", n.Message, strings.Join(n.Tags, "\x00"), n.File)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
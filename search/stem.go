@@ -0,0 +1,197 @@
+package search
+
+import "strings"
+
+// Stem reduces an English word to its stem with the Porter algorithm, so
+// "caching", "cached" and "caches" all index as "cach". Words that are not
+// plain lower-case ASCII, and words of one or two letters, are returned
+// unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := step1a(word)
+	w = step1b(w)
+	w = step1c(w)
+	w = replaceSuffix(w, step2, 0)
+	w = replaceSuffix(w, step3, 0)
+	w = step4(w)
+	return step5(w)
+}
+
+// isCons reports whether w[i] is a consonant. "y" is a consonant at the
+// start of a word and after a vowel.
+func isCons(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isCons(w, i-1)
+	}
+	return true
+}
+
+// measure returns m in the form [C](VC)^m[V] of w.
+func measure(w string) int {
+	m, i := 0, 0
+	for i < len(w) && isCons(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isCons(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && isCons(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func hasVowel(w string) bool {
+	for i := range len(w) {
+		if !isCons(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleCons reports whether w ends in a double consonant.
+func doubleCons(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isCons(w, n-1)
+}
+
+// cvc reports whether w ends consonant-vowel-consonant, where the last
+// consonant is not w, x or y, as in "hop" but not "snow".
+func cvc(w string) bool {
+	n := len(w)
+	if n < 3 || !isCons(w, n-3) || isCons(w, n-2) || !isCons(w, n-1) {
+		return false
+	}
+	return !strings.ContainsRune("wxy", rune(w[n-1]))
+}
+
+func step1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func step1b(w string) string {
+	if stem, ok := strings.CutSuffix(w, "eed"); ok {
+		if measure(stem) > 0 {
+			return stem + "ee"
+		}
+		return w
+	}
+
+	var stem string
+	if s, ok := strings.CutSuffix(w, "ed"); ok && hasVowel(s) {
+		stem = s
+	} else if s, ok := strings.CutSuffix(w, "ing"); ok && hasVowel(s) {
+		stem = s
+	} else {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case doubleCons(stem) && !strings.ContainsRune("lsz", rune(stem[len(stem)-1])):
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && cvc(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func step1c(w string) string {
+	if stem, ok := strings.CutSuffix(w, "y"); ok && hasVowel(stem) {
+		return stem + "i"
+	}
+	return w
+}
+
+var step2 = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"},
+	{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"},
+	{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3 = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"},
+	{"ful", ""}, {"ness", ""},
+}
+
+// replaceSuffix replaces the longest matching suffix of the rules when the
+// remaining stem has a measure greater than minMeasure.
+func replaceSuffix(w string, rules [][2]string, minMeasure int) string {
+	best := -1
+	for i, r := range rules {
+		if strings.HasSuffix(w, r[0]) && (best < 0 || len(r[0]) > len(rules[best][0])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(rules[best][0])]
+	if measure(stem) > minMeasure {
+		return stem + rules[best][1]
+	}
+	return w
+}
+
+var step4Suffixes = []string{
+	"ement", "ance", "ence", "able", "ible", "ment", "ant", "ent", "ism", "ate", "iti", "ous",
+	"ive", "ize", "ion", "al", "er", "ic", "ou",
+}
+
+func step4(w string) string {
+	for _, suffix := range step4Suffixes {
+		stem, ok := strings.CutSuffix(w, suffix)
+		if !ok {
+			continue
+		}
+		if suffix == "ion" && (stem == "" || !strings.ContainsRune("st", rune(stem[len(stem)-1]))) {
+			return w
+		}
+		if measure(stem) > 1 {
+			return stem
+		}
+		return w
+	}
+	return w
+}
+
+func step5(w string) string {
+	if stem, ok := strings.CutSuffix(w, "e"); ok {
+		if m := measure(stem); m > 1 || (m == 1 && !cvc(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && doubleCons(w) && strings.HasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
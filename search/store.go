@@ -0,0 +1,158 @@
+package search
+
+import (
+	"os"
+	"strings"
+
+	"spjoes/notes/store"
+)
+
+// IndexedStore is a NoteStore that updates the search index of its project
+// after every write.
+type IndexedStore struct {
+	store.NoteStore
+}
+
+var _ store.NoteStore = (*IndexedStore)(nil)
+
+// Open opens the notes of root like store.Open, keeping the search index up
+// to date when it is enabled.
+func Open(root string) (store.NoteStore, error) {
+	s, err := store.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	if Enabled(s.Root()) {
+		return &IndexedStore{s}, nil
+	}
+	return s, nil
+}
+
+// Add adds a note and indexes it.
+func (s *IndexedStore) Add(n store.Note) (store.Note, error) {
+	before := store.Fingerprint(s.Root())
+	added, err := s.NoteStore.Add(n)
+	if err == nil {
+		s.update(before, func(idx *Index) bool { return idx.Put(added) })
+	}
+	return added, err
+}
+
+// Update replaces a note and re-indexes it.
+func (s *IndexedStore) Update(n store.Note) error {
+	before := store.Fingerprint(s.Root())
+	err := s.NoteStore.Update(n)
+	if err == nil {
+		if updated, err := s.NoteStore.Get(n.ID); err == nil {
+			s.update(before, func(idx *Index) bool { return idx.Put(updated) })
+		}
+	}
+	return err
+}
+
+// Delete removes a note and drops it from the index.
+func (s *IndexedStore) Delete(id string) error {
+	// resolve a short ID before the note is gone
+	n, getErr := s.NoteStore.Get(id)
+	before := store.Fingerprint(s.Root())
+	err := s.NoteStore.Delete(id)
+	if err == nil && getErr == nil {
+		s.update(before, func(idx *Index) bool { return idx.Delete(n.ID) })
+	}
+	return err
+}
+
+// Apply makes the changes of a batch and updates the index for all of them
+// at once.
+func (s *IndexedStore) Apply(b store.Batch) ([]store.Note, error) {
+	// resolve short IDs before the notes are gone
+	deleted := make([]string, 0, len(b.Delete))
+	for _, id := range b.Delete {
+		if n, err := s.NoteStore.Get(id); err == nil {
+			deleted = append(deleted, n.ID)
+		}
+	}
+	before := store.Fingerprint(s.Root())
+	added, err := s.NoteStore.Apply(b)
+	if err != nil {
+		return nil, err
+	}
+
+	put := make([]store.Note, 0, len(added)+len(b.Update))
+	put = append(put, added...)
+	for _, n := range b.Update {
+		if updated, err := s.NoteStore.Get(n.ID); err == nil {
+			put = append(put, updated)
+		}
+	}
+	s.update(before, func(idx *Index) bool {
+		changed := false
+		for _, n := range put {
+			changed = idx.Put(n) || changed
+		}
+		for _, id := range deleted {
+			changed = idx.Delete(id) || changed
+		}
+		return changed
+	})
+	return added, nil
+}
+
+// update applies fn to the persisted index after a write. before is the
+// store fingerprint taken ahead of the write: when the index matched it, the
+// index is still in step with the notes and takes the new fingerprint, so
+// the next search need not sync. The notes themselves are already written,
+// so a failure only leaves the index stale; its file is removed so it is not
+// trusted until the next search rebuilds it.
+func (s *IndexedStore) update(before uint64, fn func(*Index) bool) {
+	root := s.Root()
+	idx := Load(root)
+	if len(idx.Docs) == 0 {
+		// a new or discarded index; Sync builds it on the next search
+		return
+	}
+	changed := fn(idx)
+	if idx.Fingerprint == before {
+		idx.Fingerprint = store.Fingerprint(root)
+		changed = true
+	}
+	if changed {
+		if err := idx.Save(root); err != nil {
+			os.Remove(Path(root))
+		}
+	}
+}
+
+// Highlight calls mark on every word of text whose stem is one of terms and
+// returns text with the marked words replaced.
+func Highlight(text string, terms []string, mark func(string) string) string {
+	want := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		want[t] = true
+	}
+
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		if want[Stem(strings.ToLower(word))] {
+			b.WriteString(mark(word))
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if isSeparator(r) {
+			flush(i)
+			b.WriteRune(r)
+		} else if start < 0 {
+			start = i
+		}
+	}
+	flush(len(text))
+	return b.String()
+}
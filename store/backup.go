@@ -0,0 +1,108 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBackups is the number of backups kept in .notes/backups.
+var MaxBackups = 10
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+// BackupDir returns the directory backups are rotated in.
+func BackupDir(root string) string {
+	return filepath.Join(root, ".notes", "backups")
+}
+
+// backupFile copies path into the backup directory, then removes the oldest
+// backups beyond MaxBackups.
+func backupFile(root, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dir := BackupDir(root)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := base + "-" + time.Now().UTC().Format(backupTimeFormat) + filepath.Ext(path)
+	if err := WriteFileAtomic(filepath.Join(dir, name), data, 0644); err != nil {
+		return err
+	}
+	return pruneBackups(dir, base)
+}
+
+func pruneBackups(dir, base string) error {
+	backups, err := listBackups(dir, base)
+	if err != nil {
+		return err
+	}
+	for len(backups) > MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// listBackups returns the backups of the file named base, oldest first.
+func listBackups(dir, base string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, base+"-*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// LatestValidBackup returns the newest backup of notes.json that parses,
+// along with its notes. It returns ErrNotFound when there is none.
+func LatestValidBackup(root string) (string, []Note, error) {
+	backups, err := listBackups(BackupDir(root), "notes")
+	if err != nil {
+		return "", nil, err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(backups[i])
+		if err != nil {
+			continue
+		}
+		var notes []Note
+		if err := json.Unmarshal(data, &notes); err == nil {
+			return backups[i], notes, nil
+		}
+	}
+	return "", nil, ErrNotFound
+}
+
+// latestValidNoteBackup returns the newest backup of a per-file note that
+// parses.
+func latestValidNoteBackup(root, id string) (Note, string, bool) {
+	backups, err := listBackups(BackupDir(root), id)
+	if err != nil {
+		return Note{}, "", false
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(backups[i])
+		if err != nil {
+			continue
+		}
+		var n Note
+		if err := json.Unmarshal(data, &n); err == nil && n.ID != "" {
+			return n, backups[i], true
+		}
+	}
+	return Note{}, "", false
+}
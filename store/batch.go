@@ -0,0 +1,65 @@
+package store
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Batch is a set of changes written to a store at once by Apply.
+type Batch struct {
+	// Add holds new notes; IDs and creation times are filled in if unset.
+	Add []Note
+	// Update holds notes replacing the stored notes with the same ID.
+	Update []Note
+	// Delete holds full or short IDs of notes to remove.
+	Delete []string
+}
+
+// prepareNew fills in the ID and creation time of a note being added and
+// normalizes its file.
+func prepareNew(root string, note Note) Note {
+	if note.ID == "" {
+		note.ID = uuid.New().String()
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+	note.File = NormalizePath(root, note.File)
+	return note
+}
+
+// applyBatch returns notes with the changes of b made, and the full IDs of
+// the deleted notes. It fails without changing notes when an updated or
+// deleted note does not exist. Added notes must already be prepared.
+func applyBatch(root string, notes []Note, b Batch) ([]Note, []string, error) {
+	out := make([]Note, len(notes))
+	copy(out, notes)
+
+	deleted := make([]string, 0, len(b.Delete))
+	for _, id := range b.Delete {
+		i, err := findIndex(out, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		deleted = append(deleted, out[i].ID)
+		out = append(out[:i], out[i+1:]...)
+	}
+
+	for _, note := range b.Update {
+		note.File = NormalizePath(root, note.File)
+		i := -1
+		for j := range out {
+			if out[j].ID == note.ID {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return nil, nil, ErrNotFound
+		}
+		out[i] = note
+	}
+
+	return append(out, b.Add...), deleted, nil
+}
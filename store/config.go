@@ -0,0 +1,71 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Storage formats selectable in .notes/config.json.
+const (
+	// StorageJSON keeps every note in a single .notes/notes.json array.
+	StorageJSON = "json"
+	// StoragePerFile keeps each note in its own .notes/notes/<id>.json file,
+	// so notes added on different branches never conflict.
+	StoragePerFile = "per-file"
+)
+
+// Config is the per-project configuration stored in .notes/config.json.
+type Config struct {
+	Storage string `json:"storage,omitempty"`
+}
+
+// ConfigPath returns the location of the project configuration file.
+func ConfigPath(root string) string {
+	return filepath.Join(root, ".notes", "config.json")
+}
+
+// LoadConfig reads the project configuration, returning the defaults when
+// there is none.
+func LoadConfig(root string) (Config, error) {
+	cfg := Config{Storage: StorageJSON}
+
+	data, err := os.ReadFile(ConfigPath(root))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", ConfigPath(root), err)
+	}
+	if cfg.Storage == "" {
+		cfg.Storage = StorageJSON
+	}
+	return cfg, cfg.validate()
+}
+
+// SaveConfig writes the project configuration.
+func SaveConfig(root string, cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ConfigPath(root)), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(ConfigPath(root), data, 0644)
+}
+
+func (c Config) validate() error {
+	switch c.Storage {
+	case StorageJSON, StoragePerFile:
+		return nil
+	}
+	return fmt.Errorf("unknown storage %q (expected %q or %q)", c.Storage, StorageJSON, StoragePerFile)
+}
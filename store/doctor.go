@@ -0,0 +1,413 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Problem kinds reported by Diagnose.
+const (
+	ProblemInvalidJSON      = "invalid-json"
+	ProblemConflictMarkers  = "conflict-markers"
+	ProblemMissingID        = "missing-id"
+	ProblemDuplicateID      = "duplicate-id"
+	ProblemMissingCreatedAt = "missing-created-at"
+	ProblemMissingFile      = "missing-file"
+)
+
+// Problem is a single issue found in a store.
+type Problem struct {
+	Kind    string
+	NoteID  string
+	Message string
+	// Fixable is true when Repair will resolve the problem.
+	Fixable bool
+}
+
+// DoctorOptions controls which optional repairs Diagnose plans.
+type DoctorOptions struct {
+	// ClearMissingFiles unlinks notes from files that no longer exist.
+	ClearMissingFiles bool
+	// Fix is set when Repair will run, so the optional repairs are reported
+	// as what will happen rather than what --fix would do.
+	Fix bool
+}
+
+// Diagnosis is the result of checking a store.
+type Diagnosis struct {
+	Problems []Problem
+
+	repaired []Note
+	// remove lists per-file note files made obsolete by the repair.
+	remove []string
+}
+
+// NeedsRepair reports whether Repair would change the store.
+func (d *Diagnosis) NeedsRepair() bool {
+	for _, p := range d.Problems {
+		if p.Fixable {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnose checks s for invalid JSON, unresolved merge conflicts, duplicate
+// or missing IDs, missing creation times and links to files that no longer
+// exist, and plans a repair for each fixable problem.
+func Diagnose(s NoteStore, opts DoctorOptions) (*Diagnosis, error) {
+	d := &Diagnosis{}
+
+	var notes []sourcedNote
+	var err error
+	switch s := s.(type) {
+	case *JSONStore:
+		notes, err = d.readJSON(s)
+	case *PerFileStore:
+		notes, err = d.readPerFile(s)
+	default:
+		return nil, fmt.Errorf("doctor does not support %T", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.check(s.Root(), notes, opts)
+	return d, nil
+}
+
+// sourcedNote is a note along with the per-file note file it was read from.
+type sourcedNote struct {
+	Note
+	path string
+}
+
+func (d *Diagnosis) readJSON(s *JSONStore) ([]sourcedNote, error) {
+	data, err := os.ReadFile(s.Path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	parseErr := json.Unmarshal(data, &notes)
+
+	if hasConflictMarkers(data) {
+		ours, theirs := splitConflict(data)
+		var oursNotes, theirsNotes []Note
+		errOurs := json.Unmarshal(ours, &oursNotes)
+		errTheirs := json.Unmarshal(theirs, &theirsNotes)
+		if errOurs == nil && errTheirs == nil {
+			notes = unionByID(oursNotes, theirsNotes)
+			parseErr = nil
+			d.add(Problem{
+				Kind:    ProblemConflictMarkers,
+				Message: fmt.Sprintf("unresolved merge conflict; both sides will be combined into %d notes", len(notes)),
+				Fixable: true,
+			})
+		} else {
+			d.add(Problem{
+				Kind:    ProblemConflictMarkers,
+				Message: "unresolved merge conflict whose sides do not parse",
+			})
+		}
+	}
+
+	if parseErr != nil {
+		backup, backupNotes, err := LatestValidBackup(s.root)
+		if err != nil {
+			d.add(Problem{
+				Kind:    ProblemInvalidJSON,
+				Message: fmt.Sprintf("%v; no valid backup to restore from", parseErr),
+			})
+			return nil, nil
+		}
+		notes = backupNotes
+		d.add(Problem{
+			Kind:    ProblemInvalidJSON,
+			Message: fmt.Sprintf("%v; %s (%d notes) will be restored", parseErr, filepath.Base(backup), len(notes)),
+			Fixable: true,
+		})
+	}
+
+	out := make([]sourcedNote, len(notes))
+	for i, n := range notes {
+		out[i] = sourcedNote{Note: n}
+	}
+	return out, nil
+}
+
+func (d *Diagnosis) readPerFile(s *PerFileStore) ([]sourcedNote, error) {
+	paths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []sourcedNote
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+
+		var n Note
+		parseErr := json.Unmarshal(data, &n)
+		if parseErr == nil {
+			out = append(out, sourcedNote{Note: n, path: path})
+			continue
+		}
+
+		if hasConflictMarkers(data) {
+			ours, _ := splitConflict(data)
+			if err := json.Unmarshal(ours, &n); err == nil {
+				d.add(Problem{
+					Kind:    ProblemConflictMarkers,
+					Message: fmt.Sprintf("%s has an unresolved merge conflict; our side will be kept", name),
+					Fixable: true,
+				})
+				out = append(out, sourcedNote{Note: n, path: path})
+				continue
+			}
+		}
+
+		if n, backup, ok := latestValidNoteBackup(s.root, strings.TrimSuffix(name, ".json")); ok {
+			d.add(Problem{
+				Kind:    ProblemInvalidJSON,
+				Message: fmt.Sprintf("%s: %v; %s will be restored", name, parseErr, filepath.Base(backup)),
+				Fixable: true,
+			})
+			out = append(out, sourcedNote{Note: n, path: path})
+			continue
+		}
+
+		d.add(Problem{
+			Kind:    ProblemInvalidJSON,
+			Message: fmt.Sprintf("%s: %v; no valid backup to restore from", name, parseErr),
+		})
+	}
+	return out, nil
+}
+
+// check looks for problems in individual notes and plans their repair.
+func (d *Diagnosis) check(root string, notes []sourcedNote, opts DoctorOptions) {
+	modTime := time.Now()
+	if info, err := os.Stat(filepath.Join(root, ".notes")); err == nil {
+		modTime = info.ModTime()
+	}
+
+	seen := map[string]Note{}
+	repaired := make([]Note, 0, len(notes))
+	for _, sn := range notes {
+		n := sn.Note
+		if n.ID == "" {
+			n.ID = uuid.New().String()
+			d.add(Problem{
+				Kind:    ProblemMissingID,
+				NoteID:  n.ID,
+				Message: fmt.Sprintf("note %q has no ID; a new one will be assigned", summary(n.Message)),
+				Fixable: true,
+			})
+		}
+
+		if prev, ok := seen[n.ID]; ok {
+			if sameNote(prev, n) {
+				d.add(Problem{
+					Kind:    ProblemDuplicateID,
+					NoteID:  n.ID,
+					Message: "note appears twice; the copy will be removed",
+					Fixable: true,
+				})
+				d.obsolete(sn.path)
+				continue
+			}
+			oldID := n.ID
+			n.ID = uuid.New().String()
+			d.add(Problem{
+				Kind:    ProblemDuplicateID,
+				NoteID:  oldID,
+				Message: fmt.Sprintf("ID is shared by different notes; %q will get ID %s", summary(n.Message), n.ShortID()),
+				Fixable: true,
+			})
+		}
+		seen[n.ID] = n
+
+		if n.CreatedAt.IsZero() {
+			n.CreatedAt = modTime
+			d.add(Problem{
+				Kind:    ProblemMissingCreatedAt,
+				NoteID:  n.ID,
+				Message: "created_at is missing; it will be set to the store's modification time",
+				Fixable: true,
+			})
+		}
+
+		if n.File != "" {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(n.File))); os.IsNotExist(err) {
+				msg := fmt.Sprintf("linked file %s no longer exists", n.File)
+				switch {
+				case opts.ClearMissingFiles && opts.Fix:
+					msg += "; the link will be removed"
+				case opts.ClearMissingFiles:
+					msg += "; the link would be removed with --fix"
+				default:
+					msg += "; use --clear-missing --fix to remove the link"
+				}
+				if opts.ClearMissingFiles {
+					n.File = ""
+					n.SetSpan(Span{})
+					n.Anchor = nil
+				}
+				d.add(Problem{
+					Kind:    ProblemMissingFile,
+					NoteID:  n.ID,
+					Message: msg,
+					Fixable: opts.ClearMissingFiles,
+				})
+			}
+		}
+
+		if sn.path != "" && filepath.Base(sn.path) != n.ID+".json" {
+			d.obsolete(sn.path)
+		}
+		repaired = append(repaired, n)
+	}
+
+	d.repaired = repaired
+}
+
+// Repair writes the repaired notes planned by Diagnose. The previous
+// contents are kept in the backup directory.
+func (d *Diagnosis) Repair(s NoteStore) error {
+	if !d.NeedsRepair() {
+		return nil
+	}
+
+	switch s := s.(type) {
+	case *JSONStore:
+		return s.Replace(d.repaired)
+	case *PerFileStore:
+		return s.locked(func() error {
+			for _, n := range d.repaired {
+				path := s.notePath(n.ID)
+				if err := backupFile(s.root, path); err != nil {
+					return err
+				}
+				if err := writeNoteFile(path, n); err != nil {
+					return err
+				}
+			}
+			for _, path := range d.remove {
+				if err := backupFile(s.root, path); err != nil {
+					return err
+				}
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return fmt.Errorf("doctor does not support %T", s)
+}
+
+func (d *Diagnosis) obsolete(path string) {
+	if path != "" {
+		d.remove = append(d.remove, path)
+	}
+}
+
+func (d *Diagnosis) add(p Problem) {
+	d.Problems = append(d.Problems, p)
+}
+
+func hasConflictMarkers(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("<<<<<<<")) || bytes.HasPrefix(line, []byte(">>>>>>>")) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitConflict returns the "ours" and "theirs" versions of a file containing
+// git conflict markers. The common ancestor section of diff3-style conflicts
+// is dropped.
+func splitConflict(data []byte) ([]byte, []byte) {
+	const (
+		both = iota
+		ours
+		base
+		theirs
+	)
+
+	var o, t bytes.Buffer
+	state := both
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<"):
+			state = ours
+			continue
+		case strings.HasPrefix(line, "|||||||") && state == ours:
+			state = base
+			continue
+		case strings.HasPrefix(line, "=======") && state != both:
+			state = theirs
+			continue
+		case strings.HasPrefix(line, ">>>>>>>"):
+			state = both
+			continue
+		}
+
+		switch state {
+		case both:
+			o.WriteString(line)
+			t.WriteString(line)
+		case ours:
+			o.WriteString(line)
+		case theirs:
+			t.WriteString(line)
+		}
+	}
+	return o.Bytes(), t.Bytes()
+}
+
+// unionByID returns every note of a followed by the notes of b whose ID is
+// not in a.
+func unionByID(a, b []Note) []Note {
+	ids := map[string]bool{}
+	out := make([]Note, 0, len(a)+len(b))
+	for _, n := range a {
+		ids[n.ID] = true
+		out = append(out, n)
+	}
+	for _, n := range b {
+		if n.ID == "" || !ids[n.ID] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func sameNote(a, b Note) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func summary(message string) string {
+	message, _, _ = strings.Cut(message, "\n")
+	if len(message) > 40 {
+		return message[:37] + "..."
+	}
+	return message
+}
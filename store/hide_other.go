@@ -1,7 +1,7 @@
 //go:build !windows
 // +build !windows
 
-package cmd
+package store
 
 // hideFile for non‐Windows is a no‐op (it does nothing).
 func hideFile(path string) error {
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JSONStore keeps every note in a single .notes/notes.json array.
+type JSONStore struct {
+	root string
+
+	// LockTimeout bounds how long writers wait for the store lock.
+	LockTimeout time.Duration
+}
+
+var _ NoteStore = (*JSONStore)(nil)
+
+// NewJSONStore returns a JSONStore for the project rooted at root.
+func NewJSONStore(root string) *JSONStore {
+	return &JSONStore{root: root, LockTimeout: DefaultLockTimeout}
+}
+
+func (s *JSONStore) Root() string {
+	return s.root
+}
+
+// Dir returns the .notes directory of the store.
+func (s *JSONStore) Dir() string {
+	return filepath.Join(s.root, ".notes")
+}
+
+// Path returns the location of notes.json.
+func (s *JSONStore) Path() string {
+	return filepath.Join(s.Dir(), "notes.json")
+}
+
+func (s *JSONStore) Load() ([]Note, error) {
+	data, err := os.ReadFile(s.Path())
+	if os.IsNotExist(err) {
+		return []Note{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := DecodeNotes(data)
+	if err != nil {
+		return nil, &CorruptError{Path: s.Path(), Err: err}
+	}
+	return notes, nil
+}
+
+// DecodeNotes parses the contents of a notes.json file.
+func DecodeNotes(data []byte) ([]Note, error) {
+	var notes []Note
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, err
+	}
+	if notes == nil {
+		notes = []Note{}
+	}
+	return notes, nil
+}
+
+// EncodeNotes formats notes the way notes.json is written.
+func EncodeNotes(notes []Note) ([]byte, error) {
+	if notes == nil {
+		notes = []Note{}
+	}
+	return json.MarshalIndent(notes, "", "  ")
+}
+
+func (s *JSONStore) Get(id string) (Note, error) {
+	notes, err := s.Load()
+	if err != nil {
+		return Note{}, err
+	}
+	i, err := findIndex(notes, id)
+	if err != nil {
+		return Note{}, err
+	}
+	return notes[i], nil
+}
+
+func (s *JSONStore) Add(note Note) (Note, error) {
+	note = prepareNew(s.root, note)
+
+	//create the notes directory if it doesn't exist
+	if err := prepareNotesDir(s.root); err != nil {
+		return Note{}, err
+	}
+
+	err := s.mutate(func(notes []Note) ([]Note, error) {
+		return append(notes, note), nil
+	})
+	if err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+func (s *JSONStore) Update(note Note) error {
+	note.File = NormalizePath(s.root, note.File)
+	return s.mutate(func(notes []Note) ([]Note, error) {
+		for i := range notes {
+			if notes[i].ID == note.ID {
+				notes[i] = note
+				return notes, nil
+			}
+		}
+		return nil, ErrNotFound
+	})
+}
+
+func (s *JSONStore) Delete(id string) error {
+	return s.mutate(func(notes []Note) ([]Note, error) {
+		i, err := findIndex(notes, id)
+		if err != nil {
+			return nil, err
+		}
+		return append(notes[:i], notes[i+1:]...), nil
+	})
+}
+
+func (s *JSONStore) Apply(b Batch) ([]Note, error) {
+	added := make([]Note, len(b.Add))
+	for i, n := range b.Add {
+		added[i] = prepareNew(s.root, n)
+	}
+	b.Add = added
+
+	if len(added) > 0 {
+		if err := prepareNotesDir(s.root); err != nil {
+			return nil, err
+		}
+	}
+	err := s.mutate(func(notes []Note) ([]Note, error) {
+		notes, _, err := applyBatch(s.root, notes, b)
+		return notes, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (s *JSONStore) Query(match func(Note) bool) ([]Note, error) {
+	notes, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return filterNotes(notes, match), nil
+}
+
+// mutate loads the notes, applies fn and writes the result back while
+// holding the store lock, so concurrent writers never lose each other's
+// changes.
+func (s *JSONStore) mutate(fn func([]Note) ([]Note, error)) error {
+	if _, err := os.Stat(s.Dir()); os.IsNotExist(err) {
+		// nothing stored yet; let fn report ErrNotFound
+		if _, err := fn([]Note{}); err != nil {
+			return err
+		}
+	}
+	lock, err := AcquireLock(s.Dir(), s.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	notes, err := s.Load()
+	if err != nil {
+		return err
+	}
+	notes, err = fn(notes)
+	if err != nil {
+		return err
+	}
+	return s.write(notes)
+}
+
+// Replace overwrites the whole store with notes without reading the current
+// contents first. It is used to repair a store that no longer parses.
+func (s *JSONStore) Replace(notes []Note) error {
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return err
+	}
+	lock, err := AcquireLock(s.Dir(), s.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// keep the contents being replaced, even if they no longer parse
+	if err := backupFile(s.root, s.Path()); err != nil {
+		return err
+	}
+	return s.write(notes)
+}
+
+// write atomically replaces notes.json and keeps a copy of the new contents
+// in the backup directory. The caller must hold the store lock.
+func (s *JSONStore) write(notes []Note) error {
+	data, err := EncodeNotes(notes)
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(s.Path(), data, 0644); err != nil {
+		return err
+	}
+	return backupFile(s.root, s.Path())
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const lockFileName = "notes.lock"
+
+var (
+	// DefaultLockTimeout is how long writers wait for another process to
+	// release the lock before giving up.
+	DefaultLockTimeout = 5 * time.Second
+
+	// StaleLockAge is the age after which a lock file is assumed to have been
+	// left behind by a crashed process and is removed.
+	StaleLockAge = 2 * time.Minute
+
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+// ErrLocked is returned when the store lock could not be acquired in time.
+var ErrLocked = errors.New("notes store is locked")
+
+// Lock is an advisory lock held on a .notes directory.
+type Lock struct {
+	path string
+}
+
+// AcquireLock takes the advisory lock for the .notes directory dir, retrying
+// until timeout elapses.
+func AcquireLock(dir string, timeout time.Duration) (*Lock, error) {
+	path := filepath.Join(dir, lockFileName)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+			f.Close()
+			return &Lock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		removed, err := removeStaleLock(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s is held by %s; if no other notes command is running, remove the file and try again",
+				ErrLocked, path, lockOwner(path))
+		}
+		if !removed {
+			time.Sleep(lockRetryInterval)
+		}
+	}
+}
+
+// removeStaleLock removes the lock file at path if it is older than
+// StaleLockAge and reports whether it did. Another process may remove the
+// same stale lock and take a new one meanwhile, so the file is checked again
+// right before removing it and kept if it is no longer the one judged stale.
+func removeStaleLock(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= StaleLockAge {
+		return false, nil
+	}
+	owner, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil
+	}
+
+	again, err := os.Stat(path)
+	if err != nil || !os.SameFile(info, again) || !again.ModTime().Equal(info.ModTime()) {
+		return false, nil
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, owner) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("removing stale lock: %w", err)
+	}
+	return true, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	err := os.Remove(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func lockOwner(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "another process"
+	}
+	pid, _, _ := strings.Cut(string(data), "\n")
+	if _, err := strconv.Atoi(pid); err != nil {
+		return "another process"
+	}
+	return "process " + pid
+}
+
+// WriteFileAtomic writes data to a temporary file next to path, flushes it
+// to disk and renames it over path, so readers never observe a partial file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
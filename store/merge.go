@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// MergeConflict describes a change made on both sides of a merge that could
+// not be reconciled. The merged result keeps "our" version.
+type MergeConflict struct {
+	NoteID string
+	// Field is the JSON name of the conflicting field, or "deleted" when one
+	// side deleted a note the other side changed.
+	Field string
+}
+
+// Merge3 performs a three-way merge of note lists keyed by Note.ID. Notes
+// added on either side are kept, notes deleted on either side are removed
+// unless the other side changed them, and edits are merged field by field.
+// Notes keep "our" order, followed by notes added only on "their" side.
+func Merge3(base, ours, theirs []Note) ([]Note, []MergeConflict) {
+	baseByID := indexNotes(base)
+	oursByID := indexNotes(ours)
+	theirsByID := indexNotes(theirs)
+
+	var merged []Note
+	var conflicts []MergeConflict
+
+	for _, o := range ours {
+		b, inBase := baseByID[o.ID]
+		t, inTheirs := theirsByID[o.ID]
+
+		switch {
+		case inTheirs:
+			n, fields := mergeNote(b, o, t, inBase)
+			for _, f := range fields {
+				conflicts = append(conflicts, MergeConflict{NoteID: o.ID, Field: f})
+			}
+			merged = append(merged, n)
+		case !inBase:
+			// added on our side
+			merged = append(merged, o)
+		case !sameNote(b, o):
+			// they deleted a note we changed; keep ours
+			conflicts = append(conflicts, MergeConflict{NoteID: o.ID, Field: "deleted"})
+			merged = append(merged, o)
+		}
+	}
+
+	for _, t := range theirs {
+		if _, ok := oursByID[t.ID]; ok {
+			continue
+		}
+		b, inBase := baseByID[t.ID]
+		switch {
+		case !inBase:
+			// added on their side
+			merged = append(merged, t)
+		case !sameNote(b, t):
+			// we deleted a note they changed; keep theirs
+			conflicts = append(conflicts, MergeConflict{NoteID: t.ID, Field: "deleted"})
+			merged = append(merged, t)
+		}
+	}
+
+	if merged == nil {
+		merged = []Note{}
+	}
+	return merged, conflicts
+}
+
+// spanFields are the JSON fields of a note's span, which are merged as one
+// unit so a merge never pairs the start of one side's span with the end of
+// the other's.
+var spanFields = []string{"line", "column", "end_line", "end_column"}
+
+// mergeNote merges the fields of a note present on both sides and returns
+// the names of the fields changed differently on each side.
+func mergeNote(base, ours, theirs Note, inBase bool) (Note, []string) {
+	b := noteFields(base)
+	if !inBase {
+		b = map[string]json.RawMessage{}
+		base = Note{}
+	}
+	o := noteFields(ours)
+	t := noteFields(theirs)
+	for _, m := range []map[string]json.RawMessage{b, o, t} {
+		for _, k := range spanFields {
+			delete(m, k)
+		}
+	}
+
+	keys := map[string]bool{}
+	for _, m := range []map[string]json.RawMessage{b, o, t} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	var conflicts []string
+	out := map[string]json.RawMessage{}
+	for k := range keys {
+		bv, ov, tv := b[k], o[k], t[k]
+		switch {
+		case bytes.Equal(ov, tv), bytes.Equal(tv, bv):
+			if ov != nil {
+				out[k] = ov
+			}
+		case bytes.Equal(ov, bv):
+			if tv != nil {
+				out[k] = tv
+			}
+		default:
+			conflicts = append(conflicts, k)
+			if ov != nil {
+				out[k] = ov
+			}
+		}
+	}
+
+	span, ok := mergeSpan(base.Span(), ours.Span(), theirs.Span())
+	if !ok {
+		conflicts = append(conflicts, "line")
+	}
+	sort.Strings(conflicts)
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return ours, conflicts
+	}
+	var n Note
+	if err := json.Unmarshal(data, &n); err != nil {
+		return ours, conflicts
+	}
+	n.SetSpan(span)
+	return n, conflicts
+}
+
+// mergeSpan merges the spans of a note as a whole, reporting false when the
+// sides changed it differently or the merged span is not valid. Our span is
+// kept then.
+func mergeSpan(base, ours, theirs Span) (Span, bool) {
+	var merged Span
+	switch {
+	case ours == theirs, theirs == base:
+		merged = ours
+	case ours == base:
+		merged = theirs
+	default:
+		return ours, false
+	}
+	if err := merged.Validate(); err != nil {
+		return ours, false
+	}
+	return merged, true
+}
+
+func noteFields(n Note) map[string]json.RawMessage {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func indexNotes(notes []Note) map[string]Note {
+	m := make(map[string]Note, len(notes))
+	for _, n := range notes {
+		m[n.ID] = n
+	}
+	return m
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	note := func(id, message string, tags ...string) Note {
+		return Note{ID: id, Message: message, Tags: tags}
+	}
+	at := func(n Note, sp Span) Note {
+		n.SetSpan(sp)
+		return n
+	}
+	a := at(note("a", "first", "bug"), Span{Line: 10, EndLine: 20})
+	b := note("b", "second")
+
+	tests := []struct {
+		name      string
+		base      []Note
+		ours      []Note
+		theirs    []Note
+		want      []Note
+		conflicts []MergeConflict
+	}{
+		{
+			name: "nothing changed",
+			base: []Note{a, b}, ours: []Note{a, b}, theirs: []Note{a, b},
+			want: []Note{a, b},
+		},
+		{
+			name: "empty",
+			want: []Note{},
+		},
+		{
+			name: "added on both sides",
+			base: []Note{a}, ours: []Note{a, b}, theirs: []Note{a, note("c", "third")},
+			want: []Note{a, b, note("c", "third")},
+		},
+		{
+			name: "added on both sides without a base",
+			ours: []Note{a}, theirs: []Note{b},
+			want: []Note{a, b},
+		},
+		{
+			name: "deleted by them",
+			base: []Note{a, b}, ours: []Note{a, b}, theirs: []Note{b},
+			want: []Note{b},
+		},
+		{
+			name: "deleted by us",
+			base: []Note{a, b}, ours: []Note{a}, theirs: []Note{a, b},
+			want: []Note{a},
+		},
+		{
+			name: "deleted by them, changed by us",
+			base: []Note{a}, ours: []Note{note("a", "changed")}, theirs: []Note{},
+			want:      []Note{note("a", "changed")},
+			conflicts: []MergeConflict{{NoteID: "a", Field: "deleted"}},
+		},
+		{
+			name: "deleted by us, changed by them",
+			base: []Note{a}, ours: []Note{}, theirs: []Note{note("a", "changed")},
+			want:      []Note{note("a", "changed")},
+			conflicts: []MergeConflict{{NoteID: "a", Field: "deleted"}},
+		},
+		{
+			name: "different fields changed",
+			base: []Note{a},
+			ours: []Note{at(note("a", "reworded", "bug"), a.Span())}, theirs: []Note{at(note("a", "first", "bug", "urgent"), a.Span())},
+			want: []Note{at(note("a", "reworded", "bug", "urgent"), a.Span())},
+		},
+		{
+			name: "same field changed differently",
+			base: []Note{a},
+			ours: []Note{at(note("a", "ours", "bug"), a.Span())}, theirs: []Note{at(note("a", "theirs", "bug"), a.Span())},
+			want:      []Note{at(note("a", "ours", "bug"), a.Span())},
+			conflicts: []MergeConflict{{NoteID: "a", Field: "message"}},
+		},
+		{
+			name: "span changed on one side",
+			base: []Note{a},
+			ours: []Note{a}, theirs: []Note{at(note("a", "first", "bug"), Span{Line: 12, EndLine: 22})},
+			want: []Note{at(note("a", "first", "bug"), Span{Line: 12, EndLine: 22})},
+		},
+		{
+			// merged field by field, the span would run from 30 to 15
+			name: "start and end of the span changed on different sides",
+			base: []Note{a},
+			ours: []Note{at(note("a", "first", "bug"), Span{Line: 30, EndLine: 40})}, theirs: []Note{at(note("a", "first", "bug"), Span{Line: 10, EndLine: 15})},
+			want:      []Note{at(note("a", "first", "bug"), Span{Line: 30, EndLine: 40})},
+			conflicts: []MergeConflict{{NoteID: "a", Field: "line"}},
+		},
+		{
+			name: "span and message changed on different sides",
+			base: []Note{a},
+			ours: []Note{at(note("a", "reworded", "bug"), a.Span())}, theirs: []Note{at(note("a", "first", "bug"), Span{Line: 11})},
+			want: []Note{at(note("a", "reworded", "bug"), Span{Line: 11})},
+		},
+		{
+			name: "invalid span taken from their side",
+			base: []Note{a},
+			ours: []Note{a}, theirs: []Note{at(note("a", "first", "bug"), Span{Line: 20, EndLine: 10})},
+			want:      []Note{a},
+			conflicts: []MergeConflict{{NoteID: "a", Field: "line"}},
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts := Merge3(tt.base, tt.ours, tt.theirs)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Merge3 = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(conflicts, tt.conflicts) {
+			t.Errorf("%s: conflicts = %+v, want %+v", tt.name, conflicts, tt.conflicts)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Migrate converts the store at root to the storage format to and records
+// the new format in .notes/config.json. The old storage is removed only
+// after every note has been written and read back unchanged; a copy is kept
+// in the backup directory. It returns the number of notes migrated.
+func Migrate(root, to string) (int, error) {
+	cfg, err := LoadConfig(root)
+	if err != nil {
+		return 0, err
+	}
+	if err := (Config{Storage: to}).validate(); err != nil {
+		return 0, err
+	}
+	if cfg.Storage == to {
+		return 0, fmt.Errorf("store already uses %s storage", to)
+	}
+
+	notesDir := filepath.Join(root, ".notes")
+	if err := prepareNotesDir(root); err != nil {
+		return 0, err
+	}
+	lock, err := AcquireLock(notesDir, DefaultLockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	from := openStorage(root, cfg.Storage)
+	notes, err := from.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	target := openStorage(root, to)
+	switch t := target.(type) {
+	case *JSONStore:
+		err = t.write(notes)
+	case *PerFileStore:
+		err = t.writeAll(notes)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := verifyMigration(notes, target); err != nil {
+		return 0, err
+	}
+
+	cfg.Storage = to
+	if err := SaveConfig(root, cfg); err != nil {
+		return 0, err
+	}
+	return len(notes), removeStorage(from)
+}
+
+// verifyMigration checks that target holds exactly the notes that were
+// migrated.
+func verifyMigration(notes []Note, target NoteStore) error {
+	got, err := target.Load()
+	if err != nil {
+		return err
+	}
+	if len(got) != len(notes) {
+		return fmt.Errorf("migration check failed: wrote %d notes but read back %d", len(notes), len(got))
+	}
+	byID := map[string]Note{}
+	for _, n := range got {
+		byID[n.ID] = n
+	}
+	for _, n := range notes {
+		if m, ok := byID[n.ID]; !ok || !sameNote(n, m) {
+			return fmt.Errorf("migration check failed: note %s differs after migration", n.ShortID())
+		}
+	}
+	return nil
+}
+
+func removeStorage(s NoteStore) error {
+	switch s := s.(type) {
+	case *JSONStore:
+		if err := backupFile(s.root, s.Path()); err != nil {
+			return err
+		}
+		if err := os.Remove(s.Path()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case *PerFileStore:
+		paths, err := s.files()
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := backupFile(s.root, path); err != nil {
+				return err
+			}
+		}
+		return os.RemoveAll(s.Dir())
+	}
+	return nil
+}
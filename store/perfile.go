@@ -0,0 +1,296 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PerFileStore keeps each note in its own .notes/notes/<id>.json file.
+// Notes added concurrently on different branches touch different files, so
+// they merge without conflicts.
+type PerFileStore struct {
+	root string
+
+	// LockTimeout bounds how long writers wait for the store lock.
+	LockTimeout time.Duration
+}
+
+var _ NoteStore = (*PerFileStore)(nil)
+
+// NewPerFileStore returns a PerFileStore for the project rooted at root.
+func NewPerFileStore(root string) *PerFileStore {
+	return &PerFileStore{root: root, LockTimeout: DefaultLockTimeout}
+}
+
+func (s *PerFileStore) Root() string {
+	return s.root
+}
+
+// Dir returns the directory holding the note files.
+func (s *PerFileStore) Dir() string {
+	return filepath.Join(s.root, ".notes", "notes")
+}
+
+func (s *PerFileStore) notePath(id string) string {
+	return filepath.Join(s.Dir(), id+".json")
+}
+
+// Load returns every note ordered by creation time.
+func (s *PerFileStore) Load() ([]Note, error) {
+	paths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]Note, 0, len(paths))
+	for _, path := range paths {
+		n, err := readNoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].CreatedAt.Equal(notes[j].CreatedAt) {
+			return notes[i].CreatedAt.Before(notes[j].CreatedAt)
+		}
+		return notes[i].ID < notes[j].ID
+	})
+	return notes, nil
+}
+
+func (s *PerFileStore) Get(id string) (Note, error) {
+	notes, err := s.Load()
+	if err != nil {
+		return Note{}, err
+	}
+	i, err := findIndex(notes, id)
+	if err != nil {
+		return Note{}, err
+	}
+	return notes[i], nil
+}
+
+func (s *PerFileStore) Add(note Note) (Note, error) {
+	note = prepareNew(s.root, note)
+
+	if err := prepareNotesDir(s.root); err != nil {
+		return Note{}, err
+	}
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return Note{}, err
+	}
+
+	err := s.locked(func() error {
+		if _, err := os.Stat(s.notePath(note.ID)); err == nil {
+			return fmt.Errorf("note %s already exists", note.ID)
+		}
+		return writeNoteFile(s.notePath(note.ID), note)
+	})
+	if err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+func (s *PerFileStore) Update(note Note) error {
+	note.File = NormalizePath(s.root, note.File)
+	return s.locked(func() error {
+		path := s.notePath(note.ID)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		if err := backupFile(s.root, path); err != nil {
+			return err
+		}
+		return writeNoteFile(path, note)
+	})
+}
+
+func (s *PerFileStore) Delete(id string) error {
+	return s.locked(func() error {
+		notes, err := s.Load()
+		if err != nil {
+			return err
+		}
+		i, err := findIndex(notes, id)
+		if err != nil {
+			return err
+		}
+
+		path := s.notePath(notes[i].ID)
+		if err := backupFile(s.root, path); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	})
+}
+
+func (s *PerFileStore) Apply(b Batch) ([]Note, error) {
+	added := make([]Note, len(b.Add))
+	for i, n := range b.Add {
+		added[i] = prepareNew(s.root, n)
+	}
+	b.Add = added
+
+	if len(added) > 0 {
+		if err := prepareNotesDir(s.root); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+			return nil, err
+		}
+	}
+	err := s.locked(func() error {
+		notes, err := s.Load()
+		if err != nil {
+			return err
+		}
+		// check every change before writing any
+		_, deleted, err := applyBatch(s.root, notes, b)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range deleted {
+			path := s.notePath(id)
+			if err := backupFile(s.root, path); err != nil {
+				return err
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		for _, note := range b.Update {
+			note.File = NormalizePath(s.root, note.File)
+			path := s.notePath(note.ID)
+			if err := backupFile(s.root, path); err != nil {
+				return err
+			}
+			if err := writeNoteFile(path, note); err != nil {
+				return err
+			}
+		}
+		for _, note := range added {
+			if err := writeNoteFile(s.notePath(note.ID), note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (s *PerFileStore) Query(match func(Note) bool) ([]Note, error) {
+	notes, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return filterNotes(notes, match), nil
+}
+
+// Replace rewrites the store so it holds exactly notes.
+func (s *PerFileStore) Replace(notes []Note) error {
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return err
+	}
+	return s.locked(func() error {
+		return s.writeAll(notes)
+	})
+}
+
+// writeAll writes every note and removes the files of notes not in the set.
+// The caller must hold the store lock.
+func (s *PerFileStore) writeAll(notes []Note) error {
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return err
+	}
+
+	keep := map[string]bool{}
+	for _, n := range notes {
+		path := s.notePath(n.ID)
+		keep[path] = true
+		if err := writeNoteFile(path, n); err != nil {
+			return err
+		}
+	}
+
+	paths, err := s.files()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if keep[path] {
+			continue
+		}
+		if err := backupFile(s.root, path); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locked runs fn while holding the store lock.
+func (s *PerFileStore) locked(fn func() error) error {
+	if _, err := os.Stat(s.Dir()); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	lock, err := AcquireLock(filepath.Dir(s.Dir()), s.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return fn()
+}
+
+// files returns the paths of every note file.
+func (s *PerFileStore) files() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.Dir(), e.Name()))
+	}
+	return paths, nil
+}
+
+func readNoteFile(path string) (Note, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Note{}, err
+	}
+	var n Note
+	if err := json.Unmarshal(data, &n); err != nil {
+		return Note{}, &CorruptError{Path: path, Err: err}
+	}
+	return n, nil
+}
+
+func writeNoteFile(path string, n Note) error {
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FindRoot returns the project root for start. It walks up the parent
+// directories looking for an existing .notes directory. The search stops at
+// the first git repository root, which becomes the project root when it has
+// no .notes directory yet; outside a repository start itself is used.
+func FindRoot(start string) (string, error) {
+	start, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	for dir := start; ; {
+		if isDir(filepath.Join(dir, ".notes")) {
+			return dir, nil
+		}
+		if exists(filepath.Join(dir, ".git")) {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return start, nil
+		}
+		dir = parent
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// exists reports whether path exists; .git may be a file in worktrees and
+// submodules.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
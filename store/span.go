@@ -0,0 +1,144 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Span is the region of a file a note refers to. Lines and columns are
+// 1-based; zero values mean "not set", so a Span with only Line set is a
+// single line.
+type Span struct {
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+}
+
+// ParseSpan parses a line specification: "42", "42-67", "42:5",
+// "42:5-67:10" or "42-67:10".
+func ParseSpan(spec string) (Span, error) {
+	var sp Span
+	start, end, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+
+	var err error
+	if sp.Line, sp.Column, err = parsePosition(start); err != nil {
+		return Span{}, fmt.Errorf("invalid line %q: %w", spec, err)
+	}
+	if isRange {
+		if sp.EndLine, sp.EndColumn, err = parsePosition(end); err != nil {
+			return Span{}, fmt.Errorf("invalid line %q: %w", spec, err)
+		}
+		if sp.EndLine < sp.Line || (sp.EndLine == sp.Line && sp.EndColumn > 0 && sp.EndColumn < sp.Column) {
+			return Span{}, fmt.Errorf("invalid line %q: range ends before it starts", spec)
+		}
+		if sp.EndLine == sp.Line && sp.EndColumn == 0 {
+			sp.EndLine = 0
+		}
+	}
+	return sp, nil
+}
+
+func parsePosition(s string) (line, col int, err error) {
+	lineStr, colStr, hasCol := strings.Cut(s, ":")
+	if line, err = strconv.Atoi(lineStr); err != nil || line < 1 {
+		return 0, 0, fmt.Errorf("line numbers start at 1")
+	}
+	if hasCol {
+		if col, err = strconv.Atoi(colStr); err != nil || col < 1 {
+			return 0, 0, fmt.Errorf("column numbers start at 1")
+		}
+	}
+	return line, col, nil
+}
+
+// Validate reports whether the span is one a note can have: the zero Span,
+// or a start line with an optional column and an end at or after it.
+func (sp Span) Validate() error {
+	switch {
+	case sp.Line < 0 || sp.Column < 0 || sp.EndLine < 0 || sp.EndColumn < 0:
+		return fmt.Errorf("invalid span %+v: line and column numbers start at 1", sp)
+	case sp.Line == 0 && sp != (Span{}):
+		return fmt.Errorf("invalid span %+v: no start line", sp)
+	case sp.EndLine == 0 && sp.EndColumn > 0:
+		return fmt.Errorf("invalid span %+v: end column without an end line", sp)
+	case sp.EndLine > 0 && sp.EndLine < sp.Line,
+		sp.EndLine == sp.Line && sp.EndColumn > 0 && sp.EndColumn < sp.Column:
+		return fmt.Errorf("invalid span %+v: range ends before it starts", sp)
+	}
+	return nil
+}
+
+// String formats the span the way ParseSpan reads it.
+func (sp Span) String() string {
+	if sp.Line <= 0 {
+		return ""
+	}
+	s := strconv.Itoa(sp.Line)
+	if sp.Column > 0 {
+		s += ":" + strconv.Itoa(sp.Column)
+	}
+	if sp.EndLine > 0 {
+		s += "-" + strconv.Itoa(sp.EndLine)
+		if sp.EndColumn > 0 {
+			s += ":" + strconv.Itoa(sp.EndColumn)
+		}
+	}
+	return s
+}
+
+// SplitFileSpan splits the "path:42-67" shorthand into the path and its
+// span. A path without a span suffix is returned unchanged with a zero Span.
+func SplitFileSpan(file string) (string, Span) {
+	base := strings.LastIndexAny(file, `/\`) + 1
+	if i := strings.Index(file[base:], ":"); i >= 0 {
+		if sp, err := ParseSpan(file[base+i+1:]); err == nil {
+			return file[:base+i], sp
+		}
+	}
+	return file, Span{}
+}
+
+// Span returns the region of the file the note refers to.
+func (n Note) Span() Span {
+	return Span{Line: n.Line, Column: n.Column, EndLine: n.EndLine, EndColumn: n.EndColumn}
+}
+
+// SetSpan sets the region of the file the note refers to.
+func (n *Note) SetSpan(sp Span) {
+	n.Line, n.Column, n.EndLine, n.EndColumn = sp.Line, sp.Column, sp.EndLine, sp.EndColumn
+}
+
+// LastLine returns the last line the note covers.
+func (n Note) LastLine() int {
+	if n.EndLine > n.Line {
+		return n.EndLine
+	}
+	return n.Line
+}
+
+// Covers reports whether the note's lines include line.
+func (n Note) Covers(line int) bool {
+	return n.Line > 0 && line >= n.Line && line <= n.LastLine()
+}
+
+// ShiftLines moves the note's span so it starts at line.
+func (n *Note) ShiftLines(line int) {
+	if n.EndLine > 0 {
+		n.EndLine += line - n.Line
+	}
+	n.Line = line
+}
+
+// Location formats the file and span of the note, e.g. "main.go:42-67".
+func (n Note) Location() string {
+	if n.File == "" {
+		return ""
+	}
+	if sp := n.Span().String(); sp != "" {
+		return filepath.ToSlash(n.File) + ":" + sp
+	}
+	return filepath.ToSlash(n.File)
+}
@@ -0,0 +1,71 @@
+package store
+
+import "testing"
+
+func TestParseSpan(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Span
+	}{
+		{"42", Span{Line: 42}},
+		{" 42 ", Span{Line: 42}},
+		{"42-67", Span{Line: 42, EndLine: 67}},
+		{"42:5", Span{Line: 42, Column: 5}},
+		{"42:5-67:10", Span{Line: 42, Column: 5, EndLine: 67, EndColumn: 10}},
+		{"42-67:10", Span{Line: 42, EndLine: 67, EndColumn: 10}},
+		{"42:5-42:9", Span{Line: 42, Column: 5, EndLine: 42, EndColumn: 9}},
+		// a range on one line without an end column is the line itself
+		{"42-42", Span{Line: 42}},
+		{"1", Span{Line: 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSpan(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSpan(%q): %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSpan(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+		if err := got.Validate(); err != nil {
+			t.Errorf("ParseSpan(%q) returned an invalid span: %v", tt.spec, err)
+		}
+		if again, err := ParseSpan(got.String()); err != nil || again != got {
+			t.Errorf("ParseSpan(%q) = %+v, want %+v", got.String(), again, got)
+		}
+	}
+}
+
+func TestParseSpanErrors(t *testing.T) {
+	for _, spec := range []string{
+		"", "0", "-1", "abc", "42-", "-42", "42:0", "42:x", "42-x",
+		"67-42", "42:9-42:5", "1-2-3",
+	} {
+		if sp, err := ParseSpan(spec); err == nil {
+			t.Errorf("ParseSpan(%q) = %+v, want an error", spec, sp)
+		}
+	}
+}
+
+func TestSpanValidate(t *testing.T) {
+	tests := []struct {
+		span  Span
+		valid bool
+	}{
+		{Span{}, true},
+		{Span{Line: 3}, true},
+		{Span{Line: 3, EndLine: 3}, true},
+		{Span{Line: 3, Column: 2, EndLine: 5, EndColumn: 1}, true},
+		{Span{Line: -1}, false},
+		{Span{Column: 4}, false},
+		{Span{EndLine: 4}, false},
+		{Span{Line: 5, EndLine: 4}, false},
+		{Span{Line: 5, Column: 8, EndLine: 5, EndColumn: 2}, false},
+		{Span{Line: 5, EndColumn: 2}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.span.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v.Validate() = %v, want valid %v", tt.span, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package store implements the note storage engine used by the notes CLI.
+//
+// Every command and the TUI go through a NoteStore, so ID matching, path
+// normalisation and error handling behave the same everywhere. Other Go
+// tools can embed the engine by opening a store for a project root.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"spjoes/notes/anchor"
+)
+
+// ShortIDLen is the number of leading ID characters accepted as a short ID.
+const ShortIDLen = 8
+
+var (
+	// ErrNotFound is returned when no note matches the requested ID.
+	ErrNotFound = errors.New("note not found")
+	// ErrAmbiguousID is returned when a short ID matches more than one note.
+	ErrAmbiguousID = errors.New("note id is ambiguous")
+	// ErrCorrupt is matched by errors returned when stored notes cannot be
+	// parsed. Writes are refused until the store is repaired.
+	ErrCorrupt = errors.New("notes store is corrupt")
+)
+
+// CorruptError reports a store file that could not be parsed.
+type CorruptError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%s cannot be parsed (%v); run `notes doctor` to repair it", e.Path, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CorruptError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+type Note struct {
+	ID        string         `json:"id"`
+	Message   string         `json:"message"`
+	File      string         `json:"file,omitempty"`
+	Line      int            `json:"line,omitempty"`
+	Column    int            `json:"column,omitempty"`
+	EndLine   int            `json:"end_line,omitempty"`
+	EndColumn int            `json:"end_column,omitempty"`
+	Anchor    *anchor.Anchor `json:"anchor,omitempty"`
+	Commit    string         `json:"commit,omitempty"`
+	// Base is the commit the file and lines were last linked at, which
+	// reanchor diffs from. Commit stays the commit the note was written at.
+	Base      string    `json:"base,omitempty"`
+	Branch    string    `json:"branch,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Tags      []string  `json:"tags,omitempty"`
+}
+
+// ReanchorBase returns the commit the note's file and lines refer to. Notes
+// written before Base was recorded refer to the commit they were written at.
+func (n Note) ReanchorBase() string {
+	if n.Base != "" {
+		return n.Base
+	}
+	return n.Commit
+}
+
+// ShortID returns the first ShortIDLen characters of the note ID.
+func (n Note) ShortID() string {
+	if len(n.ID) > ShortIDLen {
+		return n.ID[:ShortIDLen]
+	}
+	return n.ID
+}
+
+// MatchesID reports whether id is the full ID or the short ID of the note.
+func (n Note) MatchesID(id string) bool {
+	return id != "" && (n.ID == id || n.ShortID() == id)
+}
+
+// HasTag reports whether the note carries the given tag.
+func (n Note) HasTag(tag string) bool {
+	for _, t := range n.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// NoteStore is the interface every storage backend implements.
+type NoteStore interface {
+	// Root returns the project root the store belongs to.
+	Root() string
+	// Load returns every note in the store, in insertion order.
+	Load() ([]Note, error)
+	// Get returns the note matching a full or short ID.
+	Get(id string) (Note, error)
+	// Add stores a new note, filling in the ID and creation time if unset,
+	// and returns the note as saved.
+	Add(note Note) (Note, error)
+	// Update replaces the stored note that has the same ID.
+	Update(note Note) error
+	// Delete removes the note matching a full or short ID.
+	Delete(id string) error
+	// Query returns every note for which match returns true.
+	Query(match func(Note) bool) ([]Note, error)
+	// Apply makes all changes of a batch in a single write, taking a single
+	// backup, and returns the added notes as saved. Nothing is written when
+	// an updated or deleted note does not exist.
+	Apply(b Batch) ([]Note, error)
+}
+
+// Open returns the store for the project rooted at root, using the storage
+// format selected in .notes/config.json.
+func Open(root string) (NoteStore, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(abs)
+	if err != nil {
+		return nil, err
+	}
+	return openStorage(abs, cfg.Storage), nil
+}
+
+func openStorage(root, storage string) NoteStore {
+	if storage == StoragePerFile {
+		return NewPerFileStore(root)
+	}
+	return NewJSONStore(root)
+}
+
+// notesIgnore keeps files that are local to one checkout out of git.
+const notesIgnore = `# local to this checkout
+backups/
+index/
+notes.lock
+.*.tmp-*
+`
+
+// prepareNotesDir creates the .notes directory of root if needed, hides it
+// and makes sure its .gitignore lists every file local to the checkout,
+// adding the ones an older version did not know about.
+func prepareNotesDir(root string) error {
+	dir := filepath.Join(root, ".notes")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := hideFile(dir); err != nil {
+		return err
+	}
+
+	ignore := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(ignore)
+	if os.IsNotExist(err) {
+		return os.WriteFile(ignore, []byte(notesIgnore), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	present := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, line := range strings.Split(notesIgnore, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") && !present[line] {
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, strings.Join(missing, "\n")+"\n"...)
+	return WriteFileAtomic(ignore, data, 0644)
+}
+
+// Fingerprint summarizes the sizes and modification times of the files
+// holding the notes of root, so caches derived from them can tell when the
+// notes changed, including through other processes or git.
+func Fingerprint(root string) uint64 {
+	h := fnv.New64a()
+	dir := filepath.Join(root, ".notes")
+	stat := func(path string) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", filepath.Base(path), info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	stat(filepath.Join(dir, "notes.json"))
+	entries, _ := os.ReadDir(filepath.Join(dir, "notes"))
+	for _, e := range entries {
+		stat(filepath.Join(dir, "notes", e.Name()))
+	}
+	return h.Sum64()
+}
+
+// NormalizePath returns file relative to root when it lies inside it, using
+// forward slashes so stored paths are identical on every platform.
+func NormalizePath(root, file string) string {
+	if file == "" {
+		return ""
+	}
+	if filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(root, file); err == nil {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+// findIndex returns the index of the note matching id.
+func findIndex(notes []Note, id string) (int, error) {
+	idx := -1
+	for i, n := range notes {
+		if n.ID == id {
+			return i, nil
+		}
+		if n.MatchesID(id) {
+			if idx >= 0 {
+				return -1, ErrAmbiguousID
+			}
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return -1, ErrNotFound
+	}
+	return idx, nil
+}
+
+func filterNotes(notes []Note, match func(Note) bool) []Note {
+	out := []Note{}
+	for _, n := range notes {
+		if match == nil || match(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package store
+
+// syncDir for Windows is a no-op; directories cannot be opened for syncing
+// and MoveFileEx already makes the rename durable.
+func syncDir(dir string) error {
+	return nil
+}